@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the typed payload of an access token. UserID is carried in the
+// standard "sub" claim; SessionID ties the token to the refresh-token family
+// it was issued from, and the registered "jti" identifies the token itself.
+// MFAPending marks a step-up challenge that is not accepted as an access
+// token, and PendingAudience holds the audience the access token issued for
+// it will have; AMR lists the authentication methods used (RFC 8176).
+type Claims struct {
+	UserID          int              `json:"-"`
+	Roles           []string         `json:"roles,omitempty"`
+	MerchantIDs     []int            `json:"merchant_ids,omitempty"`
+	SessionID       string           `json:"sid,omitempty"`
+	MFAPending      bool             `json:"mfa_pending,omitempty"`
+	PendingAudience jwt.ClaimStrings `json:"pending_aud,omitempty"`
+	AMR             []string         `json:"amr,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+func (c *Claims) OwnsMerchant(merchantID int) bool {
+	return slices.Contains(c.MerchantIDs, merchantID)
+}
+
+func (c *Claims) resolveSubject() error {
+	if c.Subject == "" {
+		return fmt.Errorf("%w: missing sub", ErrInvalidClaims)
+	}
+
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return fmt.Errorf("%w: sub is not a user id", ErrInvalidClaims)
+	}
+
+	c.UserID = id
+	return nil
+}
+
+type ManagerOption func(*Manager)
+
+func WithIssuer(issuer string) ManagerOption {
+	return func(m *Manager) {
+		m.issuer = issuer
+	}
+}
+
+func WithDefaultTTL(ttl time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.defaultTTL = ttl
+	}
+}
+
+func WithChallengeTTL(ttl time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.challengeTTL = ttl
+	}
+}
+
+// WithAudienceTTL overrides the token lifetime for a single audience, e.g. a
+// short-lived token for the admin dashboard.
+func WithAudienceTTL(audience string, ttl time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.audienceTTL[audience] = ttl
+	}
+}
+
+// WithValidation sets the checks ValidateToken and ParseToken always apply;
+// options passed per call are applied after them.
+func WithValidation(opts ...ValidateOption) ManagerOption {
+	return func(m *Manager) {
+		m.validateOpts = append(m.validateOpts, opts...)
+	}
+}
+
+// WithRevocationStore makes every validation consult store. Wrap it with
+// NewCachedRevocationStore to avoid a database round trip per request.
+func WithRevocationStore(store RevocationStore) ManagerOption {
+	return func(m *Manager) {
+		m.revocations = store
+	}
+}
+
+type validateOptions struct {
+	audience string
+	issuer   string
+	leeway   time.Duration
+}
+
+type ValidateOption func(*validateOptions)
+
+func RequireAudience(audience string) ValidateOption {
+	return func(o *validateOptions) {
+		o.audience = audience
+	}
+}
+
+func RequireIssuer(issuer string) ValidateOption {
+	return func(o *validateOptions) {
+		o.issuer = issuer
+	}
+}
+
+func WithLeeway(leeway time.Duration) ValidateOption {
+	return func(o *validateOptions) {
+		o.leeway = leeway
+	}
+}
+
+func (o validateOptions) parserOptions() []jwt.ParserOption {
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+
+	if o.audience != "" {
+		opts = append(opts, jwt.WithAudience(o.audience))
+	}
+	if o.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(o.issuer))
+	}
+	if o.leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(o.leeway))
+	}
+
+	return opts
+}
+
+type ClaimsStore interface {
+	GetUserRoleNames(ctx context.Context, userID int32) ([]string, error)
+	GetMerchantIDsByUserID(ctx context.Context, userID int32) ([]int32, error)
+}
+
+// ClaimsBuilder loads the role names and owned merchants of a user so they
+// can be embedded in an access token.
+type ClaimsBuilder struct {
+	store ClaimsStore
+}
+
+func NewClaimsBuilder(store ClaimsStore) *ClaimsBuilder {
+	return &ClaimsBuilder{store: store}
+}
+
+func (b *ClaimsBuilder) Build(ctx context.Context, userID int, audience string) (*Claims, error) {
+	roles, err := b.store.GetUserRoleNames(ctx, int32(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user roles: %w", err)
+	}
+
+	merchantIDs, err := b.store.GetMerchantIDsByUserID(ctx, int32(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user merchants: %w", err)
+	}
+
+	claims := &Claims{
+		UserID: userID,
+		Roles:  roles,
+	}
+	for _, id := range merchantIDs {
+		claims.MerchantIDs = append(claims.MerchantIDs, int(id))
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS exports the public half of every asymmetric key in the ring, retired
+// keys included, so validators can keep verifying tokens during a rotation.
+// HMAC keys are never exported.
+func (r *KeyRing) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+
+	for _, key := range r.Keys() {
+		jwk, err := publicJWK(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func (r *KeyRing) MarshalJWKS() ([]byte, error) {
+	return json.Marshal(r.JWKS())
+}
+
+// ParseJWKS builds a verify-only KeyRing from a JWKS document.
+func ParseJWKS(data []byte) (*KeyRing, error) {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	ring, _ := NewKeyRing()
+
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.signingKey()
+		if err != nil {
+			if errors.Is(err, ErrUnsupportedKey) {
+				continue
+			}
+			return nil, err
+		}
+
+		if err := ring.Add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+func FetchJWKS(ctx context.Context, client *http.Client, url string) (*KeyRing, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwks request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks: %w", err)
+	}
+
+	return ParseJWKS(body)
+}
+
+func publicJWK(key *SigningKey) (JWK, error) {
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Kid: key.ID,
+			Alg: key.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Kid: key.ID,
+			Alg: key.Method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+}
+
+func (j JWK) signingKey() (*SigningKey, error) {
+	key := &SigningKey{ID: j.Kid, Status: KeyStatusActive}
+
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk modulus for %q: %w", j.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk exponent for %q: %w", j.Kid, err)
+		}
+
+		key.Public = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		key.Method = jwt.SigningMethodRS256
+		if j.Alg != "" {
+			method, ok := jwt.GetSigningMethod(j.Alg).(*jwt.SigningMethodRSA)
+			if !ok {
+				return nil, fmt.Errorf("%w: alg %q", ErrUnsupportedKey, j.Alg)
+			}
+			key.Method = method
+		}
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 jwk for %q", j.Kid)
+		}
+
+		key.Public = ed25519.PublicKey(x)
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%w: kty %q", ErrUnsupportedKey, j.Kty)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,348 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrKeyNotFound       = errors.New("signing key not found")
+	ErrNoActiveKey       = errors.New("no active signing key")
+	ErrKeyCannotSign     = errors.New("signing key has no private key")
+	ErrDuplicateKeyID    = errors.New("duplicate signing key id")
+	ErrUnsupportedKey    = errors.New("unsupported key type")
+	ErrSigningMethodMiss = errors.New("token signing method does not match key")
+)
+
+type KeyStatus string
+
+const (
+	KeyStatusActive  KeyStatus = "active"
+	KeyStatusRetired KeyStatus = "retired"
+)
+
+// SigningKey is a single entry of a KeyRing. Retired keys are only used to
+// verify tokens that were issued before a rotation; keys without a private
+// part (for example loaded from a JWKS document) can only verify.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.PrivateKey
+	Public    crypto.PublicKey
+	Status    KeyStatus
+	CreatedAt time.Time
+}
+
+func (k *SigningKey) CanSign() bool {
+	return k.Private != nil && k.Status == KeyStatusActive
+}
+
+func (k *SigningKey) signKey() interface{} {
+	return k.Private
+}
+
+func (k *SigningKey) verifyKey() interface{} {
+	if _, ok := k.Method.(*jwt.SigningMethodHMAC); ok {
+		return k.Private
+	}
+	return k.Public
+}
+
+func NewHMACKey(id string, secret []byte) (*SigningKey, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("empty secret key")
+	}
+
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		Private:   secret,
+		Status:    KeyStatusActive,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func GenerateRSAKey(id string, bits int) (*SigningKey, error) {
+	if bits < 2048 {
+		bits = 2048
+	}
+
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate rsa key: %w", err)
+	}
+
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodRS256,
+		Private:   priv,
+		Public:    &priv.PublicKey,
+		Status:    KeyStatusActive,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func GenerateEd25519Key(id string) (*SigningKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ed25519 key: %w", err)
+	}
+
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodEdDSA,
+		Private:   priv,
+		Public:    pub,
+		Status:    KeyStatusActive,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// ParsePrivateKeyPEM loads an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8)
+// private key, e.g. one mounted from a Kubernetes secret.
+func ParsePrivateKeyPEM(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode pem block")
+	}
+
+	var parsed interface{}
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: pem type %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	key := &SigningKey{
+		ID:        id,
+		Private:   parsed,
+		Status:    KeyStatusActive,
+		CreatedAt: time.Now(),
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.Public = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.Public = k.Public()
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, parsed)
+	}
+
+	return key, nil
+}
+
+// KeyRing holds every key a Manager may sign or verify with. The most
+// recently added active key is the primary one used for signing; tokens
+// carry its id in the "kid" header so they keep validating after rotation.
+type KeyRing struct {
+	mu      sync.RWMutex
+	keys    map[string]*SigningKey
+	primary string
+}
+
+func NewKeyRing(keys ...*SigningKey) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string]*SigningKey)}
+
+	for _, key := range keys {
+		if err := ring.Add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+func (r *KeyRing) Add(key *SigningKey) error {
+	if key == nil || key.Method == nil {
+		return ErrUnsupportedKey
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.addLocked(key)
+}
+
+func (r *KeyRing) addLocked(key *SigningKey) error {
+	if _, ok := r.keys[key.ID]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateKeyID, key.ID)
+	}
+
+	if key.Status == "" {
+		key.Status = KeyStatusActive
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	r.keys[key.ID] = key
+	if key.CanSign() {
+		r.primary = key.ID
+	}
+
+	return nil
+}
+
+// Rotate adds key as the new primary and retires every other key. Retired
+// keys stay in the ring until Remove is called so outstanding tokens remain
+// valid until they expire.
+func (r *KeyRing) Rotate(key *SigningKey) error {
+	if key == nil || key.Private == nil {
+		return ErrKeyCannotSign
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.Status = KeyStatusActive
+	if err := r.addLocked(key); err != nil {
+		return err
+	}
+
+	for id, k := range r.keys {
+		if id != key.ID {
+			k.Status = KeyStatusRetired
+		}
+	}
+
+	return nil
+}
+
+func (r *KeyRing) Retire(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	key.Status = KeyStatusRetired
+	if r.primary == id {
+		r.primary = r.newestSignerLocked()
+	}
+
+	return nil
+}
+
+func (r *KeyRing) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[id]; !ok {
+		return ErrKeyNotFound
+	}
+
+	delete(r.keys, id)
+	if r.primary == id {
+		r.primary = r.newestSignerLocked()
+	}
+
+	return nil
+}
+
+func (r *KeyRing) Primary() (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[r.primary]
+	if !ok || !key.CanSign() {
+		return nil, ErrNoActiveKey
+	}
+
+	return key, nil
+}
+
+func (r *KeyRing) Lookup(id string) (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// Keys returns a snapshot of the ring ordered from oldest to newest.
+func (r *KeyRing) Keys() []*SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys
+}
+
+func (r *KeyRing) newestSignerLocked() string {
+	var newest *SigningKey
+	for _, k := range r.keys {
+		if k.CanSign() && (newest == nil || k.CreatedAt.After(newest.CreatedAt)) {
+			newest = k
+		}
+	}
+
+	if newest == nil {
+		return ""
+	}
+	return newest.ID
+}
+
+// Keyfunc exposes the ring for verifying tokens whose claims are not Claims,
+// such as OIDC ID tokens.
+func (r *KeyRing) Keyfunc() jwt.Keyfunc {
+	return r.keyFunc
+}
+
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	var key *SigningKey
+	var err error
+
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, err = r.Lookup(kid)
+	} else {
+		key, err = r.Lookup("")
+		if err != nil {
+			key, err = r.Primary()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("%w: %v", ErrSigningMethodMiss, token.Header["alg"])
+	}
+
+	return key.verifyKey(), nil
+}
@@ -0,0 +1,445 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+	"github.com/MamangRust/monolith-ecommerce-pkg/randomstring"
+	"github.com/MamangRust/monolith-ecommerce-pkg/totp"
+)
+
+var (
+	ErrMFANotEnrolled    = errors.New("two-factor authentication not enrolled")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrMFACodeInvalid    = errors.New("invalid two-factor code")
+	ErrMFACodeReused     = errors.New("two-factor code already used")
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+
+	minSecretKeySize    = 16
+	sealedSecretVersion = "v1:"
+	maxReserveRetries   = 5
+)
+
+// DefaultMFAThrottlePolicy limits guessing second-factor codes per user:
+// five wrong codes lock two-factor verification for 15 minutes, which keeps
+// a brute force of 6-digit codes far out of reach.
+var DefaultMFAThrottlePolicy = ThrottlePolicy{
+	FreeAttempts:    2,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	MaxFailures:     5,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+type MFAStore interface {
+	UpsertUserTotp(ctx context.Context, arg db.UpsertUserTotpParams) (*db.UserTotp, error)
+	GetUserTotp(ctx context.Context, userID int32) (*db.UserTotp, error)
+	EnableUserTotp(ctx context.Context, userID int32) error
+	MarkTotpStepUsed(ctx context.Context, arg db.MarkTotpStepUsedParams) (int32, error)
+	DeleteUserTotp(ctx context.Context, userID int32) error
+	CreateRecoveryCode(ctx context.Context, arg db.CreateRecoveryCodeParams) error
+	DeleteRecoveryCodes(ctx context.Context, userID int32) error
+	UseRecoveryCode(ctx context.Context, arg db.UseRecoveryCodeParams) (int32, error)
+}
+
+// SecondFactor verifies the code a user presents after their password. It is
+// what Manager.CompleteChallenge consults before issuing a full token.
+type SecondFactor interface {
+	Verify(ctx context.Context, userID int, code string) error
+}
+
+// TOTPEnrollment is shown to the user once, as a QR code of URI or the raw
+// secret for manual entry.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+//go:generate mockgen -source=mfa.go -destination=mocks/mfa.go
+type MFAService interface {
+	SecondFactor
+	Enroll(ctx context.Context, userID int, account string) (*TOTPEnrollment, error)
+	Confirm(ctx context.Context, userID int, code string) ([]string, error)
+	IsEnabled(ctx context.Context, userID int) (bool, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID int) ([]string, error)
+	Disable(ctx context.Context, userID int) error
+}
+
+type MFAOption func(*mfaService)
+
+// WithMFAAttemptStore counts wrong codes in store instead of process
+// memory, so the limit holds across instances.
+func WithMFAAttemptStore(store LoginAttemptStore) MFAOption {
+	return func(s *mfaService) {
+		s.attempts = store
+	}
+}
+
+func WithMFAThrottlePolicy(policy ThrottlePolicy) MFAOption {
+	return func(s *mfaService) {
+		s.policy = policy
+	}
+}
+
+type mfaService struct {
+	store    MFAStore
+	config   totp.Config
+	aead     cipher.AEAD
+	attempts LoginAttemptStore
+	policy   ThrottlePolicy
+	now      func() time.Time
+}
+
+// NewMFAService stores TOTP secrets encrypted with AES-256-GCM under a key
+// derived from secretKey, which must be at least 16 bytes and kept apart
+// from the database. config is checked with totp.Config.Check. Wrong codes
+// are throttled per user following DefaultMFAThrottlePolicy.
+func NewMFAService(store MFAStore, config totp.Config, secretKey []byte, opts ...MFAOption) (MFAService, error) {
+	if err := config.Check(); err != nil {
+		return nil, err
+	}
+	if len(secretKey) < minSecretKeySize {
+		return nil, fmt.Errorf("totp secret key must be at least %d bytes", minSecretKeySize)
+	}
+
+	key := sha256.Sum256(secretKey)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create totp cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create totp cipher: %w", err)
+	}
+
+	s := &mfaService{
+		store:    store,
+		config:   config,
+		aead:     aead,
+		attempts: NewMemoryLoginAttemptStore(),
+		policy:   DefaultMFAThrottlePolicy,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Enroll generates a new secret for userID. Two-factor stays off until the
+// user proves their authenticator works by calling Confirm.
+func (s *mfaService) Enroll(ctx context.Context, userID int, account string) (*TOTPEnrollment, error) {
+	current, err := s.store.GetUserTotp(ctx, int32(userID))
+	if err == nil && current.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to find totp settings: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	sealed, err := s.sealSecret(userID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.store.UpsertUserTotp(ctx, db.UpsertUserTotpParams{
+		UserID: int32(userID),
+		Secret: sealed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store totp secret: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    s.config.URI(secret, account),
+	}, nil
+}
+
+// Confirm enables two-factor after checking the first code from the
+// authenticator and returns a fresh set of recovery codes.
+func (s *mfaService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	current, err := s.settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.throttle(ctx, userID, func() error {
+		return s.verifyTOTP(ctx, current, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.EnableUserTotp(ctx, int32(userID)); err != nil {
+		return nil, fmt.Errorf("failed to enable totp: %w", err)
+	}
+
+	return s.RegenerateRecoveryCodes(ctx, userID)
+}
+
+func (s *mfaService) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	current, err := s.store.GetUserTotp(ctx, int32(userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to find totp settings: %w", err)
+	}
+
+	return current.Enabled, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+// Once the user has too many wrong codes it returns a *LockedError.
+func (s *mfaService) Verify(ctx context.Context, userID int, code string) error {
+	current, err := s.settings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !current.Enabled {
+		return ErrMFANotEnrolled
+	}
+
+	return s.throttle(ctx, userID, func() error {
+		return s.verifyCode(ctx, current, code)
+	})
+}
+
+func (s *mfaService) verifyCode(ctx context.Context, current *db.UserTotp, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == s.config.Digits && isDigits(code) {
+		return s.verifyTOTP(ctx, current, code)
+	}
+
+	_, err := s.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserID:   current.UserID,
+		CodeHash: HashOpaqueToken(normalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMFACodeInvalid
+		}
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return nil
+}
+
+// throttle runs verify unless userID is locked out. The attempt is counted
+// as a failure, together with the lock it would earn, before verify runs,
+// so concurrent guesses cannot all pass the lock check; a valid code clears
+// the count again. Errors other than invalid and reused codes stay counted.
+func (s *mfaService) throttle(ctx context.Context, userID int, verify func() error) error {
+	key := mfaThrottleKey(userID)
+	now := s.now()
+
+	next, err := s.reserve(ctx, key, now)
+	if err != nil {
+		return err
+	}
+
+	verifyErr := verify()
+	if verifyErr == nil {
+		if err := s.attempts.Reset(ctx, key); err != nil {
+			return fmt.Errorf("failed to reset two-factor attempts: %w", err)
+		}
+		return nil
+	}
+
+	if next.LockedUntil.After(now) && (errors.Is(verifyErr, ErrMFACodeInvalid) || errors.Is(verifyErr, ErrMFACodeReused)) {
+		return errors.Join(verifyErr, &LockedError{Until: next.LockedUntil})
+	}
+
+	return verifyErr
+}
+
+// reserve records the attempt at now, retrying when a concurrent attempt
+// changed the record in between. It returns a *LockedError while key is
+// locked.
+func (s *mfaService) reserve(ctx context.Context, key string, now time.Time) (LoginAttempt, error) {
+	for range maxReserveRetries {
+		current, err := s.attempts.Get(ctx, key)
+		if err != nil {
+			return LoginAttempt{}, fmt.Errorf("failed to get two-factor attempts: %w", err)
+		}
+		if current.LockedUntil.After(now) {
+			return LoginAttempt{}, &LockedError{Until: current.LockedUntil}
+		}
+
+		next := LoginAttempt{Failures: 1, LastFailureAt: now}
+		if !current.LastFailureAt.Before(now.Add(-s.policy.Window)) {
+			next.Failures = current.Failures + 1
+		}
+		if delay := s.policy.lockFor(next.Failures); delay > 0 {
+			next.LockedUntil = now.Add(delay)
+		}
+
+		reserved, err := s.attempts.Reserve(ctx, key, current, next)
+		if err != nil {
+			return LoginAttempt{}, fmt.Errorf("failed to reserve two-factor attempt: %w", err)
+		}
+		if reserved {
+			return next, nil
+		}
+	}
+
+	return LoginAttempt{}, &LockedError{Until: now.Add(s.policy.BaseDelay)}
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of userID. Only the
+// hashes are stored, so the returned codes must be shown to the user now.
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	if err := s.store.DeleteRecoveryCodes(ctx, int32(userID)); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		raw, err := randomstring.GenerateRandomString(recoveryCodeLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		code := strings.ToLower(raw[:recoveryCodeLength/2] + "-" + raw[recoveryCodeLength/2:])
+
+		err = s.store.CreateRecoveryCode(ctx, db.CreateRecoveryCodeParams{
+			UserID:   int32(userID),
+			CodeHash: HashOpaqueToken(normalizeRecoveryCode(code)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+func (s *mfaService) Disable(ctx context.Context, userID int) error {
+	if err := s.store.DeleteRecoveryCodes(ctx, int32(userID)); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if err := s.store.DeleteUserTotp(ctx, int32(userID)); err != nil {
+		return fmt.Errorf("failed to delete totp settings: %w", err)
+	}
+	return nil
+}
+
+func (s *mfaService) settings(ctx context.Context, userID int) (*db.UserTotp, error) {
+	current, err := s.store.GetUserTotp(ctx, int32(userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("failed to find totp settings: %w", err)
+	}
+	return current, nil
+}
+
+// verifyTOTP checks code and then advances the last used time step, which
+// fails for a code (or an earlier one) that was already accepted.
+func (s *mfaService) verifyTOTP(ctx context.Context, current *db.UserTotp, code string) error {
+	secret, err := s.openSecret(current)
+	if err != nil {
+		return err
+	}
+
+	step, ok, err := s.config.Validate(secret, code, s.now())
+	if err != nil {
+		return fmt.Errorf("failed to validate totp code: %w", err)
+	}
+	if !ok {
+		return ErrMFACodeInvalid
+	}
+
+	_, err = s.store.MarkTotpStepUsed(ctx, db.MarkTotpStepUsedParams{
+		UserID:       current.UserID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMFACodeReused
+		}
+		return fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	return nil
+}
+
+// sealSecret encrypts secret for storage. The user ID is authenticated with
+// it, so a secret copied to another user's row does not decrypt.
+func (s *mfaService) sealSecret(userID int, secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate totp nonce: %w", err)
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), secretAAD(userID))
+
+	return sealedSecretVersion + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *mfaService) openSecret(current *db.UserTotp) (string, error) {
+	encoded, ok := strings.CutPrefix(current.Secret, sealedSecretVersion)
+	if !ok {
+		return "", errors.New("totp secret is not encrypted")
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return "", errors.New("malformed totp secret")
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, secretAAD(int(current.UserID)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	return string(secret), nil
+}
+
+func secretAAD(userID int) []byte {
+	return []byte("user_totp:" + strconv.Itoa(userID))
+}
+
+func mfaThrottleKey(userID int) string {
+	return "mfa:" + strconv.Itoa(userID)
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+	"github.com/MamangRust/monolith-ecommerce-pkg/randomstring"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+const (
+	refreshTokenLength     = 64
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+type RefreshTokenStore interface {
+	CreateRefreshTokenInFamily(ctx context.Context, arg db.CreateRefreshTokenInFamilyParams) (*db.RefreshToken, error)
+	FindRefreshTokenByHash(ctx context.Context, token string) (*db.RefreshToken, error)
+	ExchangeRefreshToken(ctx context.Context, arg db.ExchangeRefreshTokenParams) (*db.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeRefreshTokensByUserId(ctx context.Context, userID int32) error
+}
+
+// IssuedRefreshToken carries the plaintext token; it is never persisted and
+// must be handed to the client as-is. FamilyID identifies the login session
+// and is suitable as Claims.SessionID.
+type IssuedRefreshToken struct {
+	Token     string
+	UserID    int
+	FamilyID  string
+	ExpiresAt time.Time
+}
+
+//go:generate mockgen -source=refresh_token.go -destination=mocks/refresh_token.go
+type RefreshTokenService interface {
+	Issue(ctx context.Context, userID int) (*IssuedRefreshToken, error)
+	Rotate(ctx context.Context, token string) (*IssuedRefreshToken, error)
+	Revoke(ctx context.Context, token string) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID int) error
+}
+
+type RefreshTokenOption func(*refreshTokenService)
+
+func WithRefreshTokenTTL(ttl time.Duration) RefreshTokenOption {
+	return func(s *refreshTokenService) {
+		s.ttl = ttl
+	}
+}
+
+type refreshTokenService struct {
+	store RefreshTokenStore
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func NewRefreshTokenService(store RefreshTokenStore, opts ...RefreshTokenOption) RefreshTokenService {
+	s := &refreshTokenService{
+		store: store,
+		ttl:   defaultRefreshTokenTTL,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Issue starts a new token family, one per login. Existing families of the
+// same user are left untouched so several devices stay signed in.
+func (s *refreshTokenService) Issue(ctx context.Context, userID int) (*IssuedRefreshToken, error) {
+	return s.create(ctx, userID, uuid.NewString())
+}
+
+// Rotate exchanges token for a new one in the same family. The old token is
+// consumed and its successor stored atomically. Presenting a token that was
+// already exchanged revokes the whole family, since either the client or an
+// attacker is holding a stolen copy.
+func (s *refreshTokenService) Rotate(ctx context.Context, token string) (*IssuedRefreshToken, error) {
+	current, err := s.lookup(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.DeletedAt.Valid {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	if current.RotatedAt.Valid {
+		return nil, s.reuseDetected(ctx, current.FamilyID)
+	}
+
+	if s.now().After(current.Expiration) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	successor, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.now().Add(s.ttl)
+
+	next, err := s.store.ExchangeRefreshToken(ctx, db.ExchangeRefreshTokenParams{
+		RefreshTokenID: current.RefreshTokenID,
+		Token:          HashOpaqueToken(successor),
+		Expiration:     expiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, s.reuseDetected(ctx, current.FamilyID)
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return &IssuedRefreshToken{
+		Token:     successor,
+		UserID:    int(next.UserID),
+		FamilyID:  next.FamilyID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *refreshTokenService) Revoke(ctx context.Context, token string) error {
+	current, err := s.lookup(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return s.RevokeFamily(ctx, current.FamilyID)
+}
+
+// RevokeFamily signs out the session familyID. The empty family is refused,
+// so a token without one can never revoke anything beyond itself.
+func (s *refreshTokenService) RevokeFamily(ctx context.Context, familyID string) error {
+	if familyID == "" {
+		return fmt.Errorf("%w: missing token family", ErrRefreshTokenInvalid)
+	}
+
+	if err := s.store.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+func (s *refreshTokenService) RevokeAllForUser(ctx context.Context, userID int) error {
+	if err := s.store.RevokeRefreshTokensByUserId(ctx, int32(userID)); err != nil {
+		return fmt.Errorf("failed to revoke user refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *refreshTokenService) create(ctx context.Context, userID int, familyID string) (*IssuedRefreshToken, error) {
+	token, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.now().Add(s.ttl)
+
+	_, err = s.store.CreateRefreshTokenInFamily(ctx, db.CreateRefreshTokenInFamilyParams{
+		UserID:     int32(userID),
+		Token:      HashOpaqueToken(token),
+		Expiration: expiresAt,
+		FamilyID:   familyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &IssuedRefreshToken{
+		Token:     token,
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	token, err := randomstring.GenerateRandomString(refreshTokenLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *refreshTokenService) lookup(ctx context.Context, token string) (*db.RefreshToken, error) {
+	if token == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	current, err := s.store.FindRefreshTokenByHash(ctx, HashOpaqueToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+
+	return current, nil
+}
+
+func (s *refreshTokenService) reuseDetected(ctx context.Context, familyID string) error {
+	if err := s.RevokeFamily(ctx, familyID); err != nil {
+		return errors.Join(ErrRefreshTokenReused, err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// HashOpaqueToken returns the hex SHA-256 digest stored in place of
+// high-entropy bearer secrets such as refresh and reset tokens.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+	"github.com/MamangRust/monolith-ecommerce-pkg/randomstring"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrResetTokenInvalid = errors.New("invalid password reset token")
+	ErrResetTokenExpired = errors.New("password reset token expired")
+	ErrResetRateLimited  = errors.New("too many password reset requests")
+)
+
+const (
+	resetTokenLength       = 64
+	defaultResetTokenTTL   = 30 * time.Minute
+	defaultResetRateLimit  = 3
+	defaultResetRateWindow = time.Hour
+	maxPendingResets       = 16
+)
+
+// ResetTokenStore is implemented by db.Queries. GetUserByEmail is passed a
+// lowercased address and must match emails case-insensitively.
+type ResetTokenStore interface {
+	GetUserByEmail(ctx context.Context, email string) (*db.User, error)
+	CreateResetToken(ctx context.Context, arg db.CreateResetTokenParams) (*db.ResetToken, error)
+	ConsumeResetToken(ctx context.Context, token string) (*db.ResetToken, error)
+	DeleteResetToken(ctx context.Context, userID int32) error
+}
+
+// ResetTokenSender delivers the plaintext token to the user, usually as a
+// link in an email. It is the only place the token ever leaves the service.
+type ResetTokenSender interface {
+	SendResetToken(ctx context.Context, user *db.User, token string, expiresAt time.Time) error
+}
+
+// RateLimiter allows at most a fixed number of events per key within a
+// window.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
+//go:generate mockgen -source=reset_token.go -destination=mocks/reset_token.go
+type ResetTokenService interface {
+	RequestReset(ctx context.Context, email string) error
+	Redeem(ctx context.Context, token string) (int, error)
+}
+
+type ResetTokenOption func(*resetTokenService)
+
+func WithResetTokenTTL(ttl time.Duration) ResetTokenOption {
+	return func(s *resetTokenService) {
+		s.ttl = ttl
+	}
+}
+
+func WithResetRateLimiter(limiter RateLimiter) ResetTokenOption {
+	return func(s *resetTokenService) {
+		s.limiter = limiter
+	}
+}
+
+// WithResetLogger logs the failures of reset requests, which are processed
+// in the background and never reported to the caller.
+func WithResetLogger(logger logger.LoggerInterface) ResetTokenOption {
+	return func(s *resetTokenService) {
+		s.logger = logger
+	}
+}
+
+type resetTokenService struct {
+	store   ResetTokenStore
+	sender  ResetTokenSender
+	limiter RateLimiter
+	logger  logger.LoggerInterface
+	pending chan struct{}
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewResetTokenService creates a ResetTokenService. Unless overridden with
+// WithResetRateLimiter, each email address may request three resets an hour.
+func NewResetTokenService(store ResetTokenStore, sender ResetTokenSender, opts ...ResetTokenOption) ResetTokenService {
+	s := &resetTokenService{
+		store:   store,
+		sender:  sender,
+		limiter: NewMemoryRateLimiter(defaultResetRateLimit, defaultResetRateWindow),
+		pending: make(chan struct{}, maxPendingResets),
+		ttl:     defaultResetTokenTTL,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RequestReset issues a new reset token for email and hands it to the
+// sender, replacing any token issued before. The lookup, the token and the
+// delivery all happen in the background, so known and unknown emails get
+// the same nil result in the same time; failures are only logged, see
+// WithResetLogger. The rate limit is keyed on the address rather than the
+// account for the same reason. At most a few requests are processed at
+// once; further ones wait until ctx is done.
+func (s *resetTokenService) RequestReset(ctx context.Context, email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+
+	allowed, err := s.limiter.Allow(ctx, "reset:"+email)
+	if err != nil {
+		return fmt.Errorf("failed to check reset rate limit: %w", err)
+	}
+	if !allowed {
+		return ErrResetRateLimited
+	}
+
+	select {
+	case s.pending <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	go func(ctx context.Context) {
+		defer func() { <-s.pending }()
+
+		if err := s.issue(ctx, email); err != nil && s.logger != nil {
+			logger.FromContext(ctx, s.logger).Error("Failed to process password reset request", zap.Error(err))
+		}
+	}(context.WithoutCancel(ctx))
+
+	return nil
+}
+
+func (s *resetTokenService) issue(ctx context.Context, email string) error {
+	user, err := s.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if err := s.store.DeleteResetToken(ctx, user.UserID); err != nil {
+		return fmt.Errorf("failed to delete previous reset tokens: %w", err)
+	}
+
+	token, err := randomstring.GenerateRandomString(resetTokenLength)
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	expiresAt := s.now().Add(s.ttl)
+
+	_, err = s.store.CreateResetToken(ctx, db.CreateResetTokenParams{
+		UserID:     user.UserID,
+		Token:      HashOpaqueToken(token),
+		ExpiryDate: expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	if err := s.sender.SendResetToken(ctx, user, token, expiresAt); err != nil {
+		return fmt.Errorf("failed to send reset token: %w", err)
+	}
+
+	return nil
+}
+
+// Redeem consumes token and returns the user it was issued to. The token is
+// deleted even when it turns out to be expired, so callers should validate
+// the new password before redeeming.
+func (s *resetTokenService) Redeem(ctx context.Context, token string) (int, error) {
+	if token == "" {
+		return 0, ErrResetTokenInvalid
+	}
+
+	current, err := s.store.ConsumeResetToken(ctx, HashOpaqueToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrResetTokenInvalid
+		}
+		return 0, fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	if s.now().After(current.ExpiryDate) {
+		return 0, ErrResetTokenExpired
+	}
+
+	return int(current.UserID), nil
+}
+
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]rateWindow
+	now     func() time.Time
+}
+
+// NewMemoryRateLimiter returns a fixed-window RateLimiter kept in process
+// memory. Limits are per instance; use a shared store when running several
+// replicas.
+func NewMemoryRateLimiter(limit int, window time.Duration) RateLimiter {
+	return &memoryRateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]rateWindow),
+		now:     time.Now,
+	}
+}
+
+func (l *memoryRateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for k, w := range l.windows {
+		if !now.Before(w.resetAt) {
+			delete(l.windows, k)
+		}
+	}
+
+	w, ok := l.windows[key]
+	if !ok {
+		w = rateWindow{resetAt: now.Add(l.window)}
+	}
+
+	if w.count >= l.limit {
+		return false, nil
+	}
+
+	w.count++
+	l.windows[key] = w
+
+	return true, nil
+}
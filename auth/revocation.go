@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+)
+
+var (
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrRevocationUnavailable means the revocation store could not be
+	// queried, so whether the token is still valid is unknown.
+	ErrRevocationUnavailable = errors.New("token revocation check unavailable")
+)
+
+const defaultRevocationCacheTTL = 30 * time.Second
+
+// RevocationStore keeps access tokens that must be rejected before they
+// expire, either individually by jti or per user by issue time.
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeUserTokensBefore(ctx context.Context, userID int, before time.Time) error
+	// UserTokensRevokedBefore returns the zero time when the user never
+	// revoked all of their tokens.
+	UserTokensRevokedBefore(ctx context.Context, userID int) (time.Time, error)
+}
+
+type RevocationQueries interface {
+	RevokeToken(ctx context.Context, arg db.RevokeTokenParams) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeUserTokensBefore(ctx context.Context, arg db.RevokeUserTokensBeforeParams) error
+	GetUserTokensRevokedBefore(ctx context.Context, userID int32) (time.Time, error)
+}
+
+type postgresRevocationStore struct {
+	queries RevocationQueries
+}
+
+func NewPostgresRevocationStore(queries RevocationQueries) RevocationStore {
+	return &postgresRevocationStore{queries: queries}
+}
+
+func (s *postgresRevocationStore) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	return s.queries.RevokeToken(ctx, db.RevokeTokenParams{
+		Jti:       jti,
+		UserID:    int32(userID),
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (s *postgresRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.queries.IsTokenRevoked(ctx, jti)
+}
+
+func (s *postgresRevocationStore) RevokeUserTokensBefore(ctx context.Context, userID int, before time.Time) error {
+	return s.queries.RevokeUserTokensBefore(ctx, db.RevokeUserTokensBeforeParams{
+		UserID:        int32(userID),
+		RevokedBefore: before,
+	})
+}
+
+func (s *postgresRevocationStore) UserTokensRevokedBefore(ctx context.Context, userID int) (time.Time, error) {
+	before, err := s.queries.GetUserTokensRevokedBefore(ctx, int32(userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return before, err
+}
+
+type memoryRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[int]time.Time
+	now    func() time.Time
+}
+
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[int]time.Time),
+		now:    time.Now,
+	}
+}
+
+func (s *memoryRevocationStore) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, exp := range s.tokens {
+		if exp.Before(now) {
+			delete(s.tokens, id)
+		}
+	}
+
+	if _, ok := s.tokens[jti]; !ok {
+		s.tokens[jti] = expiresAt
+	}
+
+	return nil
+}
+
+func (s *memoryRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.tokens[jti]
+	return ok, nil
+}
+
+func (s *memoryRevocationStore) RevokeUserTokensBefore(ctx context.Context, userID int, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if before.After(s.users[userID]) {
+		s.users[userID] = before
+	}
+
+	return nil
+}
+
+func (s *memoryRevocationStore) UserTokensRevokedBefore(ctx context.Context, userID int) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.users[userID], nil
+}
+
+type cachedRevocationEntry struct {
+	revoked   bool
+	before    time.Time
+	expiresAt time.Time
+}
+
+// cachedRevocationStore keeps lookups in process memory for a short TTL so
+// validating a token does not hit the database on every request. Revocations
+// made through the cache take effect locally at once; other instances pick
+// them up when their entries expire. Expired entries are swept at most once
+// per TTL.
+type cachedRevocationStore struct {
+	store     RevocationStore
+	ttl       time.Duration
+	mu        sync.Mutex
+	tokens    map[string]cachedRevocationEntry
+	users     map[int]cachedRevocationEntry
+	nextEvict time.Time
+	now       func() time.Time
+}
+
+func NewCachedRevocationStore(store RevocationStore, ttl time.Duration) RevocationStore {
+	if ttl <= 0 {
+		ttl = defaultRevocationCacheTTL
+	}
+
+	return &cachedRevocationStore{
+		store:  store,
+		ttl:    ttl,
+		tokens: make(map[string]cachedRevocationEntry),
+		users:  make(map[int]cachedRevocationEntry),
+		now:    time.Now,
+	}
+}
+
+func (s *cachedRevocationStore) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	if err := s.store.RevokeToken(ctx, jti, userID, expiresAt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = cachedRevocationEntry{revoked: true, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *cachedRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	entry, ok := s.tokens[jti]
+	s.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.store.IsRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.evictLocked(now)
+	s.tokens[jti] = cachedRevocationEntry{revoked: revoked, expiresAt: now.Add(s.ttl)}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (s *cachedRevocationStore) RevokeUserTokensBefore(ctx context.Context, userID int, before time.Time) error {
+	if err := s.store.RevokeUserTokensBefore(ctx, userID, before); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.users, userID)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *cachedRevocationStore) UserTokensRevokedBefore(ctx context.Context, userID int) (time.Time, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	entry, ok := s.users[userID]
+	s.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.before, nil
+	}
+
+	before, err := s.store.UserTokensRevokedBefore(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	s.evictLocked(now)
+	s.users[userID] = cachedRevocationEntry{before: before, expiresAt: now.Add(s.ttl)}
+	s.mu.Unlock()
+
+	return before, nil
+}
+
+func (s *cachedRevocationStore) evictLocked(now time.Time) {
+	if now.Before(s.nextEvict) {
+		return
+	}
+	s.nextEvict = now.Add(s.ttl)
+
+	for id, entry := range s.tokens {
+		if !now.Before(entry.expiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	for id, entry := range s.users {
+		if !now.Before(entry.expiresAt) {
+			delete(s.users, id)
+		}
+	}
+}
+
+// RevokeToken puts the access token described by claims on the revocation
+// list until it expires.
+func (m *Manager) RevokeToken(ctx context.Context, claims *Claims) error {
+	if m.revocations == nil {
+		return errors.New("no revocation store configured")
+	}
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return ErrInvalidClaims
+	}
+
+	return m.revocations.RevokeToken(ctx, claims.ID, claims.UserID, claims.ExpiresAt.Time)
+}
+
+// RevokeAllForUser rejects every access token of userID issued up to now.
+// "iat" only has a resolution of whole seconds, so tokens issued later in
+// the same second, e.g. on an immediate re-login, are rejected as well.
+func (m *Manager) RevokeAllForUser(ctx context.Context, userID int) error {
+	if m.revocations == nil {
+		return errors.New("no revocation store configured")
+	}
+
+	return m.revocations.RevokeUserTokensBefore(ctx, userID, time.Now().Truncate(time.Second))
+}
+
+func (m *Manager) checkRevoked(ctx context.Context, claims *Claims) error {
+	if m.revocations == nil {
+		return nil
+	}
+
+	if claims.ID != "" {
+		revoked, err := m.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	before, err := m.revocations.UserTokensRevokedBefore(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if !before.IsZero() && (claims.IssuedAt == nil || !claims.IssuedAt.After(before)) {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+	"github.com/MamangRust/monolith-ecommerce-pkg/email"
+	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+	"go.uber.org/zap"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+const SecurityEventNewDevice = "new_device_login"
+
+type SessionStore interface {
+	CreateUserSession(ctx context.Context, arg db.CreateUserSessionParams) (*db.UserSession, error)
+	GetUserSession(ctx context.Context, sessionID string) (*db.UserSession, error)
+	GetActiveUserSessions(ctx context.Context, userID int32) ([]*db.UserSession, error)
+	HasUserSessionOnDevice(ctx context.Context, arg db.HasUserSessionOnDeviceParams) (bool, error)
+	TouchUserSession(ctx context.Context, arg db.TouchUserSessionParams) error
+	RevokeUserSession(ctx context.Context, sessionID string) error
+	RevokeOtherUserSessions(ctx context.Context, arg db.RevokeOtherUserSessionsParams) ([]string, error)
+}
+
+// ClientInfo describes the client making a login or refresh request. IP is
+// best taken from echo.Context.RealIP or the gRPC peer address. It may also
+// be a bare address, host:port, or a raw X-Forwarded-For list, read as
+// described at ClientIP; anything unparsable is stored as empty.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is one logged-in device. Its ID is the refresh-token family ID and
+// matches Claims.SessionID of the access tokens issued in it.
+type Session struct {
+	ID          string    `json:"id"`
+	UserID      int       `json:"user_id"`
+	UserAgent   string    `json:"user_agent"`
+	IP          string    `json:"ip"`
+	DeviceLabel string    `json:"device_label"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+type SecurityEvent struct {
+	Type       string
+	UserID     int
+	Session    Session
+	OccurredAt time.Time
+}
+
+type SecurityEventHandler interface {
+	HandleSecurityEvent(ctx context.Context, event SecurityEvent) error
+}
+
+//go:generate mockgen -source=session.go -destination=mocks/session.go
+type SessionService interface {
+	Start(ctx context.Context, userID int, client ClientInfo) (*IssuedRefreshToken, error)
+	Refresh(ctx context.Context, token string, client ClientInfo) (*IssuedRefreshToken, error)
+	ListSessions(ctx context.Context, userID int) ([]Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	RevokeAllExceptCurrent(ctx context.Context, userID int, currentSessionID string) error
+}
+
+type SessionOption func(*sessionService)
+
+// WithSecurityEventHandler notifies handler of new-device logins. Its
+// errors never fail the login; they are logged through WithSessionLogger.
+func WithSecurityEventHandler(handler SecurityEventHandler) SessionOption {
+	return func(s *sessionService) {
+		s.events = handler
+	}
+}
+
+func WithSessionLogger(logger logger.LoggerInterface) SessionOption {
+	return func(s *sessionService) {
+		s.logger = logger
+	}
+}
+
+// WithTrustedProxies lists the networks of the proxies in front of the
+// service, whose entries in an X-Forwarded-For list are skipped.
+func WithTrustedProxies(proxies ...netip.Prefix) SessionOption {
+	return func(s *sessionService) {
+		s.proxies = append(s.proxies, proxies...)
+	}
+}
+
+type sessionService struct {
+	store   SessionStore
+	refresh RefreshTokenService
+	events  SecurityEventHandler
+	logger  logger.LoggerInterface
+	proxies []netip.Prefix
+	now     func() time.Time
+}
+
+// NewSessionService records a session for every refresh-token family that
+// refresh issues and keeps both in sync when sessions are revoked.
+func NewSessionService(store SessionStore, refresh RefreshTokenService, opts ...SessionOption) SessionService {
+	s := &sessionService{
+		store:   store,
+		refresh: refresh,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start logs userID in on a new session. The first login from a device
+// label the user has not used before raises a SecurityEventNewDevice.
+func (s *sessionService) Start(ctx context.Context, userID int, client ClientInfo) (*IssuedRefreshToken, error) {
+	label := DeviceLabel(client.UserAgent)
+
+	known, err := s.store.HasUserSessionOnDevice(ctx, db.HasUserSessionOnDeviceParams{
+		UserID:      int32(userID),
+		DeviceLabel: label,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check known devices: %w", err)
+	}
+
+	issued, err := s.refresh.Issue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.store.CreateUserSession(ctx, db.CreateUserSessionParams{
+		SessionID:   issued.FamilyID,
+		UserID:      int32(userID),
+		UserAgent:   client.UserAgent,
+		IpAddress:   ClientIP(client.IP, s.proxies...),
+		DeviceLabel: label,
+	})
+	if err != nil {
+		if revokeErr := s.refresh.RevokeFamily(ctx, issued.FamilyID); revokeErr != nil {
+			err = errors.Join(err, revokeErr)
+		}
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if !known && s.events != nil {
+		err := s.events.HandleSecurityEvent(ctx, SecurityEvent{
+			Type:       SecurityEventNewDevice,
+			UserID:     userID,
+			Session:    toSession(session),
+			OccurredAt: s.now(),
+		})
+		if err != nil && s.logger != nil {
+			logger.FromContext(ctx, s.logger).Error("Failed to handle security event",
+				zap.String("event", SecurityEventNewDevice),
+				zap.Int("user_id", userID),
+				zap.String("session_id", session.SessionID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return issued, nil
+}
+
+// Refresh rotates token and updates the last activity and IP of its
+// session. The old token is already spent by then, so failing to update the
+// session is logged rather than returned.
+func (s *sessionService) Refresh(ctx context.Context, token string, client ClientInfo) (*IssuedRefreshToken, error) {
+	issued, err := s.refresh.Rotate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.TouchUserSession(ctx, db.TouchUserSessionParams{
+		SessionID: issued.FamilyID,
+		IpAddress: ClientIP(client.IP, s.proxies...),
+	})
+	if err != nil && s.logger != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to update session",
+			zap.String("session_id", issued.FamilyID),
+			zap.Error(err),
+		)
+	}
+
+	return issued, nil
+}
+
+func (s *sessionService) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	rows, err := s.store.GetActiveUserSessions(ctx, int32(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, toSession(row))
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs out one session of userID. Sessions of other users
+// are reported as not found.
+func (s *sessionService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	session, err := s.store.GetUserSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+
+	if int(session.UserID) != userID {
+		return ErrSessionNotFound
+	}
+
+	if err := s.refresh.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+
+	if err := s.store.RevokeUserSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sessionService) RevokeAllExceptCurrent(ctx context.Context, userID int, currentSessionID string) error {
+	revoked, err := s.store.RevokeOtherUserSessions(ctx, db.RevokeOtherUserSessionsParams{
+		UserID:    int32(userID),
+		SessionID: currentSessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	for _, id := range revoked {
+		if err := s.refresh.RevokeFamily(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toSession(row *db.UserSession) Session {
+	return Session{
+		ID:          row.SessionID,
+		UserID:      int(row.UserID),
+		UserAgent:   row.UserAgent,
+		IP:          row.IpAddress,
+		DeviceLabel: row.DeviceLabel,
+		CreatedAt:   row.CreatedAt,
+		LastSeenAt:  row.LastSeenAt,
+	}
+}
+
+// ClientIP returns the client address in raw, which may be an address,
+// host:port, or an X-Forwarded-For list. Every hop appends the address it
+// received the request from, so only the right end of the list can be
+// trusted: ClientIP skips entries from the trusted proxies and returns the
+// right-most one left, or "" when that is not a valid IP address.
+func ClientIP(raw string, trusted ...netip.Prefix) string {
+	entries := strings.Split(raw, ",")
+	for i := len(entries) - 1; i >= 0; i-- {
+		addr, ok := parseClientAddr(entries[i])
+		if !ok {
+			return ""
+		}
+		if i > 0 && isTrustedProxy(addr, trusted) {
+			continue
+		}
+		return addr.String()
+	}
+	return ""
+}
+
+func parseClientAddr(entry string) (netip.Addr, bool) {
+	entry = strings.TrimSpace(entry)
+	if host, _, err := net.SplitHostPort(entry); err == nil {
+		entry = host
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.WithZone("").Unmap(), true
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceLabel turns a User-Agent header into a short description such as
+// "Chrome on Windows". It only distinguishes common browsers and platforms;
+// anything else is "Unknown browser" or "unknown device".
+func DeviceLabel(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "okhttp") || strings.Contains(ua, "dart"):
+		browser = "App"
+	}
+
+	platform := "unknown device"
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os") || strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	return browser + " on " + platform
+}
+
+type UserLookup interface {
+	GetUserByID(ctx context.Context, userID int32) (*db.User, error)
+}
+
+type emailSecurityNotifier struct {
+	users       UserLookup
+	sender      email.Sender
+	sessionsURL string
+}
+
+// NewEmailSecurityNotifier emails users about new-device logins, linking to
+// sessionsURL where they can revoke the session.
+func NewEmailSecurityNotifier(users UserLookup, sender email.Sender, sessionsURL string) SecurityEventHandler {
+	return &emailSecurityNotifier{
+		users:       users,
+		sender:      sender,
+		sessionsURL: sessionsURL,
+	}
+}
+
+func (n *emailSecurityNotifier) HandleSecurityEvent(ctx context.Context, event SecurityEvent) error {
+	if event.Type != SecurityEventNewDevice {
+		return nil
+	}
+
+	user, err := n.users.GetUserByID(ctx, int32(event.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	msg := email.NewDeviceLoginMessage(
+		user.Email,
+		user.Firstname,
+		event.Session.DeviceLabel,
+		event.Session.IP,
+		event.OccurredAt,
+		n.sessionsURL,
+	)
+
+	return n.sender.Send(ctx, msg)
+}
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+)
+
+var ErrLoginLocked = errors.New("too many failed login attempts")
+
+// LockedError reports that logins are blocked until Until. It matches
+// ErrLoginLocked with errors.Is.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, locked until %s", ErrLoginLocked, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLoginLocked
+}
+
+// RetryAfter is the wait until the lock ends, for a Retry-After header.
+func (e *LockedError) RetryAfter(now time.Time) time.Duration {
+	if d := e.Until.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// LoginAttempt is the failure state of one throttling key.
+type LoginAttempt struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+type LoginAttemptStore interface {
+	// Get returns the zero LoginAttempt for keys without failures.
+	Get(ctx context.Context, key string) (LoginAttempt, error)
+	// RecordFailure atomically counts a failure at now. The count restarts
+	// when the previous failure happened before windowStart.
+	RecordFailure(ctx context.Context, key string, now time.Time, windowStart time.Time) (LoginAttempt, error)
+	// Reserve atomically replaces the state of key with next if it still
+	// equals current, as read by Get, and reports whether it did.
+	Reserve(ctx context.Context, key string, current LoginAttempt, next LoginAttempt) (bool, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// ThrottlePolicy configures one kind of key. The first FreeAttempts failures
+// cost nothing; after that each failure locks the key for BaseDelay,
+// doubling up to MaxDelay, and reaching MaxFailures locks it for
+// LockoutDuration. Failures older than Window are forgotten.
+type ThrottlePolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	MaxFailures     int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// DefaultEmailThrottlePolicy protects a single account.
+var DefaultEmailThrottlePolicy = ThrottlePolicy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	MaxFailures:     10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// DefaultIPThrottlePolicy is looser, since many users can share an address
+// behind NAT, and catches one client spraying passwords across accounts.
+var DefaultIPThrottlePolicy = ThrottlePolicy{
+	FreeAttempts:    20,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	MaxFailures:     100,
+	LockoutDuration: time.Hour,
+	Window:          time.Hour,
+}
+
+func (p ThrottlePolicy) lockFor(failures int) time.Duration {
+	if p.MaxFailures > 0 && failures >= p.MaxFailures {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay
+}
+
+//go:generate mockgen -source=throttle.go -destination=mocks/throttle.go
+type LoginThrottler interface {
+	Check(ctx context.Context, email string, ip string) error
+	RecordFailure(ctx context.Context, email string, ip string) error
+	RecordSuccess(ctx context.Context, email string) error
+	UnlockEmail(ctx context.Context, email string) error
+	UnlockIP(ctx context.Context, ip string) error
+}
+
+type ThrottleOption func(*loginThrottler)
+
+func WithEmailThrottlePolicy(policy ThrottlePolicy) ThrottleOption {
+	return func(t *loginThrottler) {
+		t.emailPolicy = policy
+	}
+}
+
+func WithIPThrottlePolicy(policy ThrottlePolicy) ThrottleOption {
+	return func(t *loginThrottler) {
+		t.ipPolicy = policy
+	}
+}
+
+type loginThrottler struct {
+	store       LoginAttemptStore
+	emailPolicy ThrottlePolicy
+	ipPolicy    ThrottlePolicy
+	now         func() time.Time
+}
+
+// NewLoginThrottler guards password checks by email and by client IP. Call
+// Check before ComparePassword, then RecordFailure or RecordSuccess with
+// the outcome; an empty ip skips the per-IP limit.
+func NewLoginThrottler(store LoginAttemptStore, opts ...ThrottleOption) LoginThrottler {
+	t := &loginThrottler{
+		store:       store,
+		emailPolicy: DefaultEmailThrottlePolicy,
+		ipPolicy:    DefaultIPThrottlePolicy,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Check returns a *LockedError when either the email or the IP is locked.
+func (t *loginThrottler) Check(ctx context.Context, email string, ip string) error {
+	now := t.now()
+
+	var until time.Time
+	for _, key := range t.keys(email, ip) {
+		attempt, err := t.store.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get login attempts: %w", err)
+		}
+		if attempt.LockedUntil.After(now) && attempt.LockedUntil.After(until) {
+			until = attempt.LockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &LockedError{Until: until}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed login and returns a *LockedError when the
+// failure locked the email or the IP.
+func (t *loginThrottler) RecordFailure(ctx context.Context, email string, ip string) error {
+	now := t.now()
+
+	var until time.Time
+	for _, key := range t.keys(email, ip) {
+		policy := t.policyFor(key)
+
+		attempt, err := t.store.RecordFailure(ctx, key, now, now.Add(-policy.Window))
+		if err != nil {
+			return fmt.Errorf("failed to record login failure: %w", err)
+		}
+
+		delay := policy.lockFor(attempt.Failures)
+		if delay <= 0 {
+			continue
+		}
+
+		lockedUntil := now.Add(delay)
+		if err := t.store.Lock(ctx, key, lockedUntil); err != nil {
+			return fmt.Errorf("failed to lock login: %w", err)
+		}
+		if lockedUntil.After(until) {
+			until = lockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &LockedError{Until: until}
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the failures of email. The IP counter is left alone
+// so one valid account cannot be used to reset it.
+func (t *loginThrottler) RecordSuccess(ctx context.Context, email string) error {
+	return t.UnlockEmail(ctx, email)
+}
+
+// UnlockEmail lifts the lock on an account, e.g. after a password reset or
+// by an administrator.
+func (t *loginThrottler) UnlockEmail(ctx context.Context, email string) error {
+	if err := t.store.Reset(ctx, emailThrottleKey(email)); err != nil {
+		return fmt.Errorf("failed to unlock email: %w", err)
+	}
+	return nil
+}
+
+func (t *loginThrottler) UnlockIP(ctx context.Context, ip string) error {
+	if err := t.store.Reset(ctx, ipThrottleKey(ip)); err != nil {
+		return fmt.Errorf("failed to unlock ip: %w", err)
+	}
+	return nil
+}
+
+func (t *loginThrottler) keys(email string, ip string) []string {
+	keys := []string{emailThrottleKey(email)}
+	if ip != "" {
+		keys = append(keys, ipThrottleKey(ip))
+	}
+	return keys
+}
+
+func (t *loginThrottler) policyFor(key string) ThrottlePolicy {
+	if strings.HasPrefix(key, "ip:") {
+		return t.ipPolicy
+	}
+	return t.emailPolicy
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+type LoginAttemptQueries interface {
+	GetLoginAttempt(ctx context.Context, attemptKey string) (*db.LoginAttempt, error)
+	RecordLoginFailure(ctx context.Context, arg db.RecordLoginFailureParams) (*db.LoginAttempt, error)
+	ReserveLoginAttempt(ctx context.Context, arg db.ReserveLoginAttemptParams) (int64, error)
+	LockLoginAttempt(ctx context.Context, arg db.LockLoginAttemptParams) error
+	DeleteLoginAttempt(ctx context.Context, attemptKey string) error
+}
+
+type postgresLoginAttemptStore struct {
+	queries LoginAttemptQueries
+}
+
+func NewPostgresLoginAttemptStore(queries LoginAttemptQueries) LoginAttemptStore {
+	return &postgresLoginAttemptStore{queries: queries}
+}
+
+func (s *postgresLoginAttemptStore) Get(ctx context.Context, key string) (LoginAttempt, error) {
+	attempt, err := s.queries.GetLoginAttempt(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return LoginAttempt{}, nil
+		}
+		return LoginAttempt{}, err
+	}
+	return toLoginAttempt(attempt), nil
+}
+
+func (s *postgresLoginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, windowStart time.Time) (LoginAttempt, error) {
+	attempt, err := s.queries.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+		AttemptKey:    key,
+		LastFailureAt: now,
+		WindowStart:   windowStart,
+	})
+	if err != nil {
+		return LoginAttempt{}, err
+	}
+	return toLoginAttempt(attempt), nil
+}
+
+func (s *postgresLoginAttemptStore) Reserve(ctx context.Context, key string, current LoginAttempt, next LoginAttempt) (bool, error) {
+	rows, err := s.queries.ReserveLoginAttempt(ctx, db.ReserveLoginAttemptParams{
+		AttemptKey:            key,
+		Failures:              int32(next.Failures),
+		LastFailureAt:         next.LastFailureAt,
+		LockedUntil:           sql.NullTime{Time: next.LockedUntil, Valid: !next.LockedUntil.IsZero()},
+		ExpectedFailures:      int32(current.Failures),
+		ExpectedLastFailureAt: current.LastFailureAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (s *postgresLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.queries.LockLoginAttempt(ctx, db.LockLoginAttemptParams{
+		AttemptKey:  key,
+		LockedUntil: sql.NullTime{Time: until, Valid: true},
+	})
+}
+
+func (s *postgresLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.queries.DeleteLoginAttempt(ctx, key)
+}
+
+func toLoginAttempt(attempt *db.LoginAttempt) LoginAttempt {
+	return LoginAttempt{
+		Failures:      int(attempt.Failures),
+		LastFailureAt: attempt.LastFailureAt,
+		LockedUntil:   attempt.LockedUntil.Time,
+	}
+}
+
+type memoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttempt
+}
+
+func NewMemoryLoginAttemptStore() LoginAttemptStore {
+	return &memoryLoginAttemptStore{
+		attempts: make(map[string]LoginAttempt),
+	}
+}
+
+func (s *memoryLoginAttemptStore) Get(ctx context.Context, key string) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts[key], nil
+}
+
+func (s *memoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, windowStart time.Time) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[key]
+	if attempt.LastFailureAt.Before(windowStart) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+
+	return attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) Reserve(ctx context.Context, key string, current LoginAttempt, next LoginAttempt) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.attempts[key]
+	if stored.Failures != current.Failures || !stored.LastFailureAt.Equal(current.LastFailureAt) {
+		return false, nil
+	}
+	s.attempts[key] = next
+
+	return true, nil
+}
+
+func (s *memoryLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[key]
+	attempt.LockedUntil = until
+	s.attempts[key] = attempt
+
+	return nil
+}
+
+func (s *memoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+
+	return nil
+}
@@ -1,15 +1,36 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var ErrTokenExpired = errors.New("token expired")
+var (
+	ErrTokenExpired  = errors.New("token expired")
+	ErrInvalidClaims = errors.New("invalid token claims")
+	ErrMFARequired   = errors.New("two-factor authentication required")
+)
+
+const (
+	defaultTokenTTL     = 12 * time.Hour
+	defaultChallengeTTL = 5 * time.Minute
+)
+
+// ChallengeAudience and ChallengeType mark step-up challenge tokens. The
+// dedicated audience makes any validator that requires its own audience
+// reject a challenge, even one that knows nothing about mfa_pending.
+const (
+	ChallengeAudience = "mfa-challenge"
+	ChallengeType     = "mfa-challenge+jwt"
+)
 
 //go:generate mockgen -source=token.go -destination=mocks/token.go
 type TokenManager interface {
@@ -17,51 +38,319 @@ type TokenManager interface {
 	ValidateToken(tokenString string) (string, error)
 }
 
+// ClaimsTokenManager issues and verifies tokens carrying the full Claims,
+// including step-up challenges. It is kept apart from TokenManager so
+// existing implementations of that interface still satisfy it.
+type ClaimsTokenManager interface {
+	TokenManager
+	GenerateTokenWithClaims(claims *Claims) (string, error)
+	ParseToken(tokenString string, opts ...ValidateOption) (*Claims, error)
+	ParseTokenContext(ctx context.Context, tokenString string, opts ...ValidateOption) (*Claims, error)
+	GenerateChallengeToken(claims *Claims) (string, error)
+	CompleteChallenge(ctx context.Context, challenge string, factor SecondFactor, code string) (string, error)
+}
+
+var _ ClaimsTokenManager = (*Manager)(nil)
+
 type Manager struct {
-	secretKey []byte
+	keys         *KeyRing
+	issuer       string
+	defaultTTL   time.Duration
+	challengeTTL time.Duration
+	audienceTTL  map[string]time.Duration
+	validateOpts []ValidateOption
+	revocations  RevocationStore
+	challenges   *challengeLedger
 }
 
-func NewManager(secretKey string) (*Manager, error) {
+func NewManager(secretKey string, opts ...ManagerOption) (*Manager, error) {
 	if secretKey == "" {
 		return nil, errors.New("empty secret key")
 	}
-	return &Manager{secretKey: []byte(secretKey)}, nil
+
+	key, err := NewHMACKey("", []byte(secretKey))
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := NewKeyRing(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewManagerWithKeyRing(keys, opts...)
+}
+
+// NewManagerWithKeyRing creates a Manager that signs with the primary key of
+// keys and verifies against every key in it. A ring built by ParseJWKS gives
+// a validate-only Manager for services that never issue tokens.
+func NewManagerWithKeyRing(keys *KeyRing, opts ...ManagerOption) (*Manager, error) {
+	if keys == nil {
+		return nil, errors.New("empty key ring")
+	}
+
+	m := &Manager{
+		keys:         keys,
+		defaultTTL:   defaultTokenTTL,
+		challengeTTL: defaultChallengeTTL,
+		audienceTTL:  make(map[string]time.Duration),
+		challenges:   newChallengeLedger(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+func (m *Manager) KeyRing() *KeyRing {
+	return m.keys
 }
 
 func (m *Manager) GenerateToken(userId int, audience string) (string, error) {
+	return m.GenerateTokenWithClaims(&Claims{
+		UserID: userId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{audience},
+		},
+	})
+}
+
+// GenerateTokenWithClaims signs a copy of claims with the primary key.
+// Subject, issuer, jti and the issued-at/expiry timestamps are filled in when
+// left empty; the lifetime comes from the TTL configured for the first
+// audience. claims itself is not modified.
+func (m *Manager) GenerateTokenWithClaims(claims *Claims) (string, error) {
+	if claims == nil {
+		return "", fmt.Errorf("%w: missing user id", ErrInvalidClaims)
+	}
+	if claims.MFAPending {
+		return "", fmt.Errorf("%w: use GenerateChallengeToken for challenges", ErrInvalidClaims)
+	}
+
+	signed := *claims
+	return m.sign(&signed, "")
+}
+
+// sign fills in the registered claims and signs them, setting the "typ"
+// header when typ is not empty.
+func (m *Manager) sign(claims *Claims, typ string) (string, error) {
+	if claims == nil || claims.UserID <= 0 {
+		return "", fmt.Errorf("%w: missing user id", ErrInvalidClaims)
+	}
+
+	key, err := m.keys.Primary()
+	if err != nil {
+		return "", err
+	}
+
 	nowTime := time.Now()
-	expireTime := nowTime.Add(12 * time.Hour)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(expireTime),
-		Subject:   strconv.Itoa(userId),
-		Audience:  []string{audience},
-	})
+	claims.Subject = strconv.Itoa(claims.UserID)
+	if claims.Issuer == "" {
+		claims.Issuer = m.issuer
+	}
+	if claims.ID == "" {
+		claims.ID = uuid.NewString()
+	}
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(nowTime)
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(nowTime.Add(m.ttlFor(claims.Audience)))
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 
-	return token.SignedString([]byte(m.secretKey))
+	return token.SignedString(key.signKey())
 }
 
 func (m *Manager) ValidateToken(accessToken string) (string, error) {
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (i interface{}, err error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	claims, err := m.ParseToken(accessToken)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
+func (m *Manager) ParseToken(accessToken string, opts ...ValidateOption) (*Claims, error) {
+	return m.ParseTokenContext(context.Background(), accessToken, opts...)
+}
+
+// ParseTokenContext verifies accessToken and returns its claims. opts are
+// applied on top of the defaults set with WithValidation, overriding only the
+// settings they name. When a RevocationStore is configured the token is also
+// checked against it; when the store cannot be queried the error wraps
+// ErrRevocationUnavailable. Step-up challenges are rejected with
+// ErrMFARequired.
+func (m *Manager) ParseTokenContext(ctx context.Context, accessToken string, opts ...ValidateOption) (*Claims, error) {
+	return m.parse(ctx, accessToken, opts, false)
+}
+
+// GenerateChallengeToken issues a short-lived step-up challenge for claims,
+// returned by login instead of an access token when the user has two-factor
+// enabled. The challenge is typed ChallengeType and addressed to
+// ChallengeAudience, so it is never accepted as an access token; only
+// CompleteChallenge takes it.
+func (m *Manager) GenerateChallengeToken(claims *Claims) (string, error) {
+	if claims == nil {
+		return "", fmt.Errorf("%w: missing user id", ErrInvalidClaims)
+	}
+
+	challenge := *claims
+	challenge.MFAPending = true
+	challenge.PendingAudience = claims.Audience
+	challenge.Audience = jwt.ClaimStrings{ChallengeAudience}
+	challenge.ID = ""
+	challenge.IssuedAt = nil
+	challenge.ExpiresAt = jwt.NewNumericDate(time.Now().Add(m.challengeTTL))
+
+	return m.sign(&challenge, ChallengeType)
+}
+
+// CompleteChallenge verifies code with factor and exchanges challenge for a
+// full access token carrying the same claims. Each challenge is accepted
+// once: this Manager remembers the ones it completed until they expire, and
+// a configured RevocationStore shares that with other instances. Limiting
+// wrong codes is up to factor, see NewMFAService.
+func (m *Manager) CompleteChallenge(ctx context.Context, challenge string, factor SecondFactor, code string) (string, error) {
+	claims, err := m.parse(ctx, challenge, nil, true)
+	if err != nil {
+		return "", err
+	}
+
+	if err := factor.Verify(ctx, claims.UserID, code); err != nil {
+		return "", err
+	}
+
+	if !m.challenges.consume(claims.ID, claims.ExpiresAt.Time) {
+		return "", ErrTokenRevoked
+	}
+
+	if m.revocations != nil {
+		if err := m.RevokeToken(ctx, claims); err != nil {
+			return "", fmt.Errorf("failed to revoke challenge token: %w", err)
 		}
+	}
 
-		return []byte(m.secretKey), nil
-	})
+	claims.MFAPending = false
+	claims.Audience = claims.PendingAudience
+	claims.PendingAudience = nil
+	claims.AMR = append(claims.AMR, "mfa")
+	claims.ID = ""
+	claims.IssuedAt = nil
+	claims.ExpiresAt = nil
+
+	return m.GenerateTokenWithClaims(claims)
+}
+
+// parse verifies a token of the expected kind: a step-up challenge when
+// challenge is set, an access token otherwise.
+func (m *Manager) parse(ctx context.Context, accessToken string, opts []ValidateOption, challenge bool) (*Claims, error) {
+	var o validateOptions
+	for _, opt := range m.validateOpts {
+		opt(&o)
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if challenge {
+		o.audience = ChallengeAudience
+	}
 
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(accessToken, claims, m.keys.keyFunc, o.parserOptions()...)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return "", ErrTokenExpired
+			return nil, ErrTokenExpired
+		}
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	if isChallenge(token, claims) != challenge {
+		if challenge {
+			return nil, fmt.Errorf("%w: not a challenge token", ErrInvalidClaims)
 		}
-		return "", fmt.Errorf("failed to parse token: %w", err)
+		return nil, ErrMFARequired
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", fmt.Errorf("error get user claims from token")
+	if err := claims.resolveSubject(); err != nil {
+		return nil, err
 	}
 
-	return claims["sub"].(string), nil
+	if err := m.checkRevoked(ctx, claims); err != nil {
+		if errors.Is(err, ErrTokenRevoked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrRevocationUnavailable, err)
+	}
+
+	return claims, nil
+}
+
+func (m *Manager) ttlFor(audience jwt.ClaimStrings) time.Duration {
+	if len(audience) > 0 {
+		if ttl, ok := m.audienceTTL[audience[0]]; ok {
+			return ttl
+		}
+	}
+	return m.defaultTTL
+}
+
+// isChallenge reports whether token carries any of the challenge markers,
+// so a token missing one of them is still treated as a challenge.
+func isChallenge(token *jwt.Token, claims *Claims) bool {
+	if typ, _ := token.Header["typ"].(string); typ == ChallengeType {
+		return true
+	}
+	return claims.MFAPending || slices.Contains(claims.Audience, ChallengeAudience)
+}
+
+// challengeLedgerGrace keeps completed challenges past their expiry, so they
+// stay rejected while a validation leeway would still accept them.
+const challengeLedgerGrace = 5 * time.Minute
+
+// challengeLedger remembers the challenges this process completed.
+type challengeLedger struct {
+	mu    sync.Mutex
+	used  map[string]time.Time
+	swept time.Time
+	now   func() time.Time
+}
+
+func newChallengeLedger() *challengeLedger {
+	return &challengeLedger{
+		used: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// consume records jti and reports whether it had not been recorded before.
+func (l *challengeLedger) consume(jti string, expiresAt time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.swept) > time.Minute {
+		for id, until := range l.used {
+			if now.After(until) {
+				delete(l.used, id)
+			}
+		}
+		l.swept = now
+	}
+
+	if _, ok := l.used[jti]; ok {
+		return false
+	}
+	l.used[jti] = expiresAt.Add(challengeLedgerGrace)
 
+	return true
 }
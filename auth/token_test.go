@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTokenKeepsValidationDefaultsWithCallOptions(t *testing.T) {
+	newManager := func(issuer string) *Manager {
+		m, err := NewManager("test-secret-key-0123456789",
+			WithIssuer(issuer),
+			WithValidation(RequireIssuer("auth-service"), RequireAudience("api")),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	m := newManager("auth-service")
+	leeway := WithLeeway(time.Minute)
+
+	valid, err := m.GenerateToken(1, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ParseTokenContext(context.Background(), valid, leeway); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	wrongAudience, err := m.GenerateToken(1, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ParseTokenContext(context.Background(), wrongAudience, leeway); err == nil {
+		t.Fatal("token for another audience accepted")
+	}
+
+	wrongIssuer, err := newManager("other-service").GenerateToken(1, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ParseTokenContext(context.Background(), wrongIssuer, leeway); err == nil {
+		t.Fatal("token from another issuer accepted")
+	}
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+	"github.com/MamangRust/monolith-ecommerce-pkg/email"
+	"github.com/MamangRust/monolith-ecommerce-pkg/randomstring"
+)
+
+var (
+	ErrVerificationInvalid  = errors.New("invalid verification code")
+	ErrVerificationExpired  = errors.New("verification code expired")
+	ErrVerificationAttempts = errors.New("too many verification attempts")
+	ErrVerificationCooldown = errors.New("verification code sent too recently")
+	ErrAlreadyVerified      = errors.New("user already verified")
+)
+
+const (
+	defaultVerificationCodeLength  = 6
+	verificationLinkTokenLength    = 48
+	defaultVerificationTTL         = 15 * time.Minute
+	defaultVerificationMaxAttempts = 5
+	defaultVerificationCooldown    = time.Minute
+)
+
+// VerificationStore is implemented by db.Queries. GetUserByEmail is passed
+// a lowercased address and must match emails case-insensitively.
+type VerificationStore interface {
+	GetUserByEmail(ctx context.Context, email string) (*db.User, error)
+	GetUserVerification(ctx context.Context, userID int32) (*db.UserVerification, error)
+	GetUserVerificationByCode(ctx context.Context, code string) (*db.UserVerification, error)
+	IncrementUserVerificationAttempts(ctx context.Context, userID int32) (int32, error)
+	UpsertUserVerification(ctx context.Context, arg db.UpsertUserVerificationParams) (*db.UserVerification, error)
+	VerifyUserWithCode(ctx context.Context, arg db.VerifyUserWithCodeParams) (*db.User, error)
+}
+
+// VerificationEmailFunc renders the email for a new code. In link mode code
+// is the complete verification URL.
+type VerificationEmailFunc func(user *db.User, code string, expiresAt time.Time) email.Message
+
+//go:generate mockgen -source=verification.go -destination=mocks/verification.go
+type VerificationService interface {
+	Send(ctx context.Context, user *db.User) error
+	VerifyCode(ctx context.Context, email string, code string) (*db.User, error)
+	VerifyLink(ctx context.Context, token string) (*db.User, error)
+}
+
+type VerificationOption func(*verificationService)
+
+func WithVerificationTTL(ttl time.Duration) VerificationOption {
+	return func(s *verificationService) {
+		s.ttl = ttl
+	}
+}
+
+func WithVerificationMaxAttempts(attempts int) VerificationOption {
+	return func(s *verificationService) {
+		s.maxAttempts = int32(attempts)
+	}
+}
+
+func WithVerificationCooldown(cooldown time.Duration) VerificationOption {
+	return func(s *verificationService) {
+		s.cooldown = cooldown
+	}
+}
+
+func WithVerificationCodeLength(length int) VerificationOption {
+	return func(s *verificationService) {
+		s.codeLength = length
+	}
+}
+
+// WithVerificationLink switches from numeric codes to high-entropy link
+// tokens, sent as baseURL with a "token" query parameter.
+func WithVerificationLink(baseURL string) VerificationOption {
+	return func(s *verificationService) {
+		s.linkURL = baseURL
+	}
+}
+
+func WithVerificationEmail(render VerificationEmailFunc) VerificationOption {
+	return func(s *verificationService) {
+		s.render = render
+	}
+}
+
+type verificationService struct {
+	store       VerificationStore
+	key         []byte
+	sender      email.Sender
+	render      VerificationEmailFunc
+	linkURL     string
+	codeLength  int
+	ttl         time.Duration
+	maxAttempts int32
+	cooldown    time.Duration
+	now         func() time.Time
+}
+
+// NewVerificationService creates a VerificationService that sends 6-digit
+// codes valid for 15 minutes, accepts 5 wrong guesses per code and allows a
+// resend once a minute. Codes are stored as HMAC-SHA256 under secretKey,
+// which must be at least 16 bytes and kept apart from the database, so a
+// leaked table cannot be brute-forced offline.
+func NewVerificationService(store VerificationStore, sender email.Sender, secretKey []byte, opts ...VerificationOption) (VerificationService, error) {
+	if len(secretKey) < minSecretKeySize {
+		return nil, fmt.Errorf("verification secret key must be at least %d bytes", minSecretKeySize)
+	}
+
+	s := &verificationService{
+		store:       store,
+		key:         secretKey,
+		sender:      sender,
+		render:      defaultVerificationEmail,
+		codeLength:  defaultVerificationCodeLength,
+		ttl:         defaultVerificationTTL,
+		maxAttempts: defaultVerificationMaxAttempts,
+		cooldown:    defaultVerificationCooldown,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Send issues a fresh code for user and emails it, replacing any pending
+// code and resetting its attempts counter. Resends within the cooldown are
+// rejected with ErrVerificationCooldown.
+func (s *verificationService) Send(ctx context.Context, user *db.User) error {
+	if user.IsVerified.Bool {
+		return ErrAlreadyVerified
+	}
+
+	now := s.now()
+
+	pending, err := s.store.GetUserVerification(ctx, user.UserID)
+	switch {
+	case err == nil:
+		if now.Before(pending.LastSentAt.Add(s.cooldown)) {
+			return ErrVerificationCooldown
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to find pending verification: %w", err)
+	}
+
+	code, err := s.generateCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification code: %w", err)
+	}
+
+	expiresAt := now.Add(s.ttl)
+
+	_, err = s.store.UpsertUserVerification(ctx, db.UpsertUserVerificationParams{
+		UserID:     user.UserID,
+		Code:       s.hash(code),
+		ExpiresAt:  expiresAt,
+		LastSentAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store verification code: %w", err)
+	}
+
+	if s.linkURL != "" {
+		code = s.link(code)
+	}
+
+	if err := s.sender.Send(ctx, s.render(user, code, expiresAt)); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyCode checks a numeric code typed in by the user. Every wrong guess
+// counts against the pending code; once the limit is reached only a resend
+// helps.
+func (s *verificationService) VerifyCode(ctx context.Context, emailAddr string, code string) (*db.User, error) {
+	if code == "" {
+		return nil, ErrVerificationInvalid
+	}
+
+	user, err := s.store.GetUserByEmail(ctx, strings.ToLower(strings.TrimSpace(emailAddr)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVerificationInvalid
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user.IsVerified.Bool {
+		return nil, ErrAlreadyVerified
+	}
+
+	pending, err := s.pending(s.store.GetUserVerification(ctx, user.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	hashed := s.hash(code)
+	if subtle.ConstantTimeCompare([]byte(hashed), []byte(pending.Code)) != 1 {
+		attempts, err := s.store.IncrementUserVerificationAttempts(ctx, user.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record verification attempt: %w", err)
+		}
+		if attempts >= s.maxAttempts {
+			return nil, ErrVerificationAttempts
+		}
+		return nil, ErrVerificationInvalid
+	}
+
+	return s.verify(ctx, user.UserID, hashed)
+}
+
+// VerifyLink checks a token from a verification link.
+func (s *verificationService) VerifyLink(ctx context.Context, token string) (*db.User, error) {
+	if token == "" {
+		return nil, ErrVerificationInvalid
+	}
+
+	hashed := s.hash(token)
+
+	pending, err := s.pending(s.store.GetUserVerificationByCode(ctx, hashed))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.verify(ctx, pending.UserID, hashed)
+}
+
+func (s *verificationService) pending(pending *db.UserVerification, err error) (*db.UserVerification, error) {
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVerificationInvalid
+		}
+		return nil, fmt.Errorf("failed to find pending verification: %w", err)
+	}
+
+	if s.now().After(pending.ExpiresAt) {
+		return nil, ErrVerificationExpired
+	}
+	if pending.Attempts >= s.maxAttempts {
+		return nil, ErrVerificationAttempts
+	}
+
+	return pending, nil
+}
+
+// verify consumes the code and flags the user in a single statement, so two
+// concurrent requests cannot both succeed.
+func (s *verificationService) verify(ctx context.Context, userID int32, hashed string) (*db.User, error) {
+	user, err := s.store.VerifyUserWithCode(ctx, db.VerifyUserWithCodeParams{
+		UserID:   userID,
+		Code:     hashed,
+		Attempts: s.maxAttempts,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVerificationInvalid
+		}
+		return nil, fmt.Errorf("failed to verify user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *verificationService) hash(code string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *verificationService) generateCode() (string, error) {
+	if s.linkURL != "" {
+		return randomstring.GenerateRandomString(verificationLinkTokenLength)
+	}
+	return randomstring.GenerateNumericCode(s.codeLength)
+}
+
+func (s *verificationService) link(token string) string {
+	u, err := url.Parse(s.linkURL)
+	if err != nil {
+		return s.linkURL + "?token=" + url.QueryEscape(token)
+	}
+
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// defaultVerificationEmail escapes the user's name, as GenerateEmailHTML
+// renders with text/template.
+func defaultVerificationEmail(user *db.User, code string, expiresAt time.Time) email.Message {
+	data := map[string]string{
+		"Subject": "Verify your email address",
+		"Title":   "Verify your email address",
+		"Button":  "Verify Email",
+	}
+
+	minutes := int(time.Until(expiresAt).Round(time.Minute).Minutes())
+
+	if strings.HasPrefix(code, "http://") || strings.HasPrefix(code, "https://") {
+		data["Message"] = fmt.Sprintf("Hi %s, click the button below to verify your email address. The link expires in %d minutes.", html.EscapeString(user.Firstname), minutes)
+		data["Link"] = code
+	} else {
+		data["Message"] = fmt.Sprintf("Hi %s, your verification code is %s. It expires in %d minutes.", html.EscapeString(user.Firstname), code, minutes)
+	}
+
+	return email.Message{
+		Email:   user.Email,
+		Subject: data["Subject"],
+		Body:    email.GenerateEmailHTML(data),
+	}
+}
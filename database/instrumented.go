@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const instrumentationName = "github.com/MamangRust/monolith-ecommerce-pkg/database"
+
+type InstrumentOption func(*instrumentConfig)
+
+type instrumentConfig struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	system         attribute.KeyValue
+	slowQuery      time.Duration
+}
+
+func WithTracerProvider(provider trace.TracerProvider) InstrumentOption {
+	return func(c *instrumentConfig) {
+		c.tracerProvider = provider
+	}
+}
+
+func WithMeterProvider(provider metric.MeterProvider) InstrumentOption {
+	return func(c *instrumentConfig) {
+		c.meterProvider = provider
+	}
+}
+
+// WithDBSystem sets the db.system attribute; it defaults to the DB_DRIVER
+// NewClient connects with.
+func WithDBSystem(system string) InstrumentOption {
+	return func(c *instrumentConfig) {
+		c.system = semconv.DBSystemKey.String(system)
+	}
+}
+
+// WithSlowQueryThreshold logs a warning for every call that takes longer
+// than d. Zero disables slow-query logging.
+func WithSlowQueryThreshold(d time.Duration) InstrumentOption {
+	return func(c *instrumentConfig) {
+		c.slowQuery = d
+	}
+}
+
+// InstrumentedDB is a db.DBTX that traces and times every call made by the
+// sqlc Queries. Spans are named after the sqlc query, e.g. GetUserByID.
+//
+//	queries := db.New(database.NewInstrumentedDB(conn, logger))
+//
+// Queries.WithTx bypasses the wrapper; use db.New(instrumented.WithTx(tx))
+// inside transactions instead.
+type InstrumentedDB struct {
+	db        db.DBTX
+	logger    logger.LoggerInterface
+	tracer    trace.Tracer
+	duration  metric.Float64Histogram
+	errors    metric.Int64Counter
+	system    attribute.KeyValue
+	slowQuery time.Duration
+}
+
+// NewInstrumentedDB wraps a *sql.DB or *sql.Tx. The slow-query threshold
+// defaults to DB_SLOW_QUERY_THRESHOLD, or 500ms when unset.
+func NewInstrumentedDB(conn db.DBTX, logger logger.LoggerInterface, opts ...InstrumentOption) *InstrumentedDB {
+	cfg := instrumentConfig{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		system:         dbSystem(viper.GetString("DB_DRIVER")),
+		slowQuery:      500 * time.Millisecond,
+	}
+	if viper.IsSet("DB_SLOW_QUERY_THRESHOLD") {
+		cfg.slowQuery = viper.GetDuration("DB_SLOW_QUERY_THRESHOLD")
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	meter := cfg.meterProvider.Meter(instrumentationName)
+
+	duration, err := meter.Float64Histogram(
+		semconv.DBClientOperationDurationName,
+		metric.WithDescription(semconv.DBClientOperationDurationDescription),
+		metric.WithUnit(semconv.DBClientOperationDurationUnit),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10),
+	)
+	if err != nil {
+		otel.Handle(fmt.Errorf("failed to create db duration histogram: %w", err))
+	}
+
+	errCounter, err := meter.Int64Counter(
+		"db.client.operation.errors",
+		metric.WithDescription("Number of database client operations that failed."),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		otel.Handle(fmt.Errorf("failed to create db error counter: %w", err))
+	}
+
+	return &InstrumentedDB{
+		db:        conn,
+		logger:    logger,
+		tracer:    cfg.tracerProvider.Tracer(instrumentationName),
+		duration:  duration,
+		errors:    errCounter,
+		system:    cfg.system,
+		slowQuery: cfg.slowQuery,
+	}
+}
+
+// WithTx returns a wrapper around tx that shares d's instruments.
+func (d *InstrumentedDB) WithTx(tx *sql.Tx) *InstrumentedDB {
+	clone := *d
+	clone.db = tx
+	return &clone
+}
+
+func (d *InstrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := d.start(ctx, query)
+	result, err := d.db.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+func (d *InstrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, done := d.start(ctx, query)
+	stmt, err := d.db.PrepareContext(ctx, query)
+	done(err)
+	return stmt, err
+}
+
+// QueryContext measures the query until the first rows are available;
+// iterating over them is not included.
+func (d *InstrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := d.start(ctx, query)
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (d *InstrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := d.start(ctx, query)
+	row := d.db.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+func (d *InstrumentedDB) start(ctx context.Context, query string) (context.Context, func(error)) {
+	name := queryName(query)
+	attrs := []attribute.KeyValue{d.system, semconv.DBOperationName(name)}
+
+	ctx, span := d.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(semconv.DBQueryText(query)),
+	)
+	started := time.Now()
+
+	return ctx, func(err error) {
+		elapsed := time.Since(started)
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			attrs = append(attrs, semconv.ErrorTypeKey.String(fmt.Sprintf("%T", err)))
+			if d.errors != nil {
+				d.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+			}
+		}
+
+		if d.duration != nil {
+			d.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attrs...))
+		}
+
+		if d.slowQuery > 0 && elapsed > d.slowQuery && d.logger != nil {
+			logger.FromContext(ctx, d.logger).Warn("Slow database query",
+				zap.String("query", name),
+				zap.Duration("duration", elapsed),
+				zap.Duration("threshold", d.slowQuery),
+			)
+		}
+
+		span.End()
+	}
+}
+
+// queryName extracts GetUserByID from the "-- name: GetUserByID :one"
+// header sqlc puts in front of every query, falling back to the SQL verb.
+func queryName(query string) string {
+	trimmed := strings.TrimSpace(query)
+
+	if rest, ok := strings.CutPrefix(trimmed, "-- name:"); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	if fields := strings.Fields(trimmed); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+
+	return "query"
+}
+
+func dbSystem(driver string) attribute.KeyValue {
+	switch driver {
+	case "postgres", "pgx":
+		return semconv.DBSystemPostgreSQL
+	case "mysql":
+		return semconv.DBSystemMySQL
+	case "":
+		return semconv.DBSystemOtherSQL
+	default:
+		return semconv.DBSystemKey.String(driver)
+	}
+}
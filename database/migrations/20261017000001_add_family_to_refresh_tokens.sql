@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id VARCHAR(64) NULL,
+    ADD COLUMN rotated_at TIMESTAMP NULL;
+
+-- Tokens issued before families existed each become a family of their own,
+-- so revoking one of them never revokes another user's tokens.
+UPDATE refresh_tokens SET family_id = md5(refresh_token_id::text);
+
+-- Rows still inserted without a family get a unique one as well.
+ALTER TABLE refresh_tokens
+    ALTER COLUMN family_id SET DEFAULT md5(random()::text || clock_timestamp()::text),
+    ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens (token);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS rotated_at,
+    DROP COLUMN IF EXISTS family_id;
+-- +goose StatementEnd
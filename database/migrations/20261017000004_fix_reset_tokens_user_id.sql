@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE reset_tokens
+    ALTER COLUMN user_id TYPE INTEGER;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reset_tokens_token ON reset_tokens (token);
+CREATE INDEX IF NOT EXISTS idx_reset_tokens_user_id ON reset_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_reset_tokens_user_id;
+DROP INDEX IF EXISTS idx_reset_tokens_token;
+
+ALTER TABLE reset_tokens
+    ALTER COLUMN user_id TYPE BIGINT;
+-- +goose StatementEnd
@@ -0,0 +1,77 @@
+-- GetLoginAttempt: Retrieves the failed login state of a throttling key
+-- Purpose: Check whether an email or IP is currently locked
+-- Parameters:
+--   $1: attempt_key - Throttling key, e.g. "email:<address>" or "ip:<address>"
+-- Returns: The attempt record, if any failures were recorded
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts WHERE attempt_key = $1;
+
+-- RecordLoginFailure: Counts a failed login for a throttling key
+-- Purpose: Drive exponential backoff and lockout
+-- Parameters:
+--   $1: attempt_key - Throttling key
+--   $2: last_failure_at - Time of the failed attempt
+--   $3: window_start - Failures before this time are forgotten
+-- Returns: The updated attempt record
+-- Business Logic:
+--   - Increments atomically so concurrent guesses are all counted
+--   - Restarts the count when the previous failure is outside the window
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (attempt_key, failures, last_failure_at)
+VALUES (sqlc.arg(attempt_key), 1, sqlc.arg(last_failure_at))
+ON CONFLICT (attempt_key) DO UPDATE
+SET failures = CASE
+        WHEN login_attempts.last_failure_at < sqlc.arg(window_start) THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failure_at = EXCLUDED.last_failure_at
+RETURNING *;
+
+-- ReserveLoginAttempt: Counts an attempt before its outcome is known
+-- Purpose: Throttle checks that must not race, such as two-factor codes
+-- Parameters:
+--   $1: attempt_key - Throttling key
+--   $2: failures - Failure count including this attempt
+--   $3: last_failure_at - Time of this attempt
+--   $4: locked_until - Lock that applies if this attempt fails, if any
+--   $5: expected_failures - Failure count read before the attempt
+--   $6: expected_last_failure_at - Last failure time read before the attempt
+-- Returns: 1 when the attempt was reserved, 0 when another attempt changed the record first
+-- Business Logic:
+--   - Compare-and-swap, so concurrent attempts cannot both pass the lock check
+--   - Inserts the record when none exists yet
+-- name: ReserveLoginAttempt :execrows
+INSERT INTO login_attempts (attempt_key, failures, last_failure_at, locked_until)
+VALUES (sqlc.arg(attempt_key), sqlc.arg(failures), sqlc.arg(last_failure_at), sqlc.narg(locked_until))
+ON CONFLICT (attempt_key) DO UPDATE
+SET failures = EXCLUDED.failures,
+    last_failure_at = EXCLUDED.last_failure_at,
+    locked_until = EXCLUDED.locked_until
+WHERE login_attempts.failures = sqlc.arg(expected_failures)
+  AND login_attempts.last_failure_at = sqlc.arg(expected_last_failure_at);
+
+-- LockLoginAttempt: Locks a throttling key until the given time
+-- Purpose: Apply a backoff delay or a lockout
+-- Parameters:
+--   $1: attempt_key - Throttling key
+--   $2: locked_until - End of the lock
+-- name: LockLoginAttempt :exec
+UPDATE login_attempts
+SET locked_until = $2
+WHERE attempt_key = $1;
+
+-- DeleteLoginAttempt: Clears the failed login state of a throttling key
+-- Purpose: Reset after a successful login or an unlock
+-- Parameters:
+--   $1: attempt_key - Throttling key
+-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts WHERE attempt_key = $1;
+
+-- DeleteStaleLoginAttempts: Removes attempt records that no longer matter
+-- Purpose: Periodic cleanup
+-- Parameters:
+--   $1: last_failure_at - Records whose last failure and lock ended before this time are deleted
+-- name: DeleteStaleLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE last_failure_at < $1
+  AND (locked_until IS NULL OR locked_until < $1);
@@ -107,6 +107,22 @@ WHERE merchant_id = $1
   AND deleted_at IS NULL;
 
 
+-- GetMerchantIDsByUserID: Retrieves IDs of active merchants owned by a user
+-- Purpose: Populate ownership claims when issuing access tokens
+-- Parameters:
+--   $1: user_id - Owner user ID
+-- Returns: List of merchant IDs
+-- Business Logic:
+--   - Excludes soft-deleted records
+--   - Orders by merchant_id for stable token contents
+-- name: GetMerchantIDsByUserID :many
+SELECT merchant_id
+FROM merchants
+WHERE user_id = $1
+  AND deleted_at IS NULL
+ORDER BY merchant_id;
+
+
 -- UpdateMerchant: Modifies merchant information
 -- Purpose: Update merchant profile details
 -- Parameters:
@@ -0,0 +1,134 @@
+-- CreatePermission: Inserts a new permission
+-- Purpose: Define a permission in resource:action[:scope] form (e.g. product:write:own)
+-- Parameters:
+--   $1: Permission name
+--   $2: Description (nullable)
+-- Returns:
+--   Newly created permission record
+-- name: CreatePermission :one
+INSERT INTO permissions (
+    name,
+    description,
+    created_at,
+    updated_at
+) VALUES (
+    $1,
+    $2,
+    current_timestamp,
+    current_timestamp
+) RETURNING *;
+
+
+-- GetPermissionByName: Retrieves an active permission by name
+-- Purpose: Lookup permission before assigning it to a role
+-- Parameters:
+--   $1: Permission name
+-- Returns:
+--   Permission record if found and active
+-- name: GetPermissionByName :one
+SELECT *
+FROM permissions
+WHERE name = $1
+  AND deleted_at IS NULL;
+
+
+-- GetPermissions: Retrieves all active permissions
+-- Purpose: List permissions for role management screens
+-- Returns:
+--   All non-deleted permissions ordered by name
+-- name: GetPermissions :many
+SELECT *
+FROM permissions
+WHERE deleted_at IS NULL
+ORDER BY name ASC;
+
+
+-- AssignPermissionToRole: Grants a permission to a role
+-- Purpose: Build the role -> permission mapping
+-- Parameters:
+--   $1: Role ID
+--   $2: Permission ID
+-- Returns:
+--   The role-permission mapping
+-- Business Logic:
+--   - Idempotent; granting an existing mapping only refreshes updated_at
+-- name: AssignPermissionToRole :one
+INSERT INTO role_permissions (
+    role_id,
+    permission_id,
+    created_at,
+    updated_at
+) VALUES (
+    $1,
+    $2,
+    current_timestamp,
+    current_timestamp
+)
+ON CONFLICT (role_id, permission_id) DO UPDATE
+SET updated_at = current_timestamp
+RETURNING *;
+
+
+-- RemovePermissionFromRole: Revokes a permission from a role
+-- Purpose: Hard delete of a role-permission mapping
+-- Parameters:
+--   $1: Role ID
+--   $2: Permission ID
+-- name: RemovePermissionFromRole :exec
+DELETE FROM role_permissions
+WHERE 
+    role_id = $1 
+    AND permission_id = $2;
+
+
+-- GetRolePermissions: Retrieves all active permissions granted to a role
+-- Purpose: Display the permissions of a role
+-- Parameters:
+--   $1: Role ID
+-- Returns:
+--   List of permission records
+-- name: GetRolePermissions :many
+SELECT
+    p.permission_id,
+    p.name,
+    p.description,
+    p.created_at,
+    p.updated_at,
+    p.deleted_at
+FROM
+    permissions p
+JOIN
+    role_permissions rp ON rp.permission_id = p.permission_id
+WHERE
+    rp.role_id = $1
+    AND p.deleted_at IS NULL
+ORDER BY
+    p.name ASC;
+
+
+-- GetUserPermissionNames: Retrieves the distinct permission names a user holds through their roles
+-- Purpose: Input of the policy evaluator
+-- Parameters:
+--   $1: User ID
+-- Returns:
+--   List of permission names
+-- Business Logic:
+--   - Ignores trashed user-role mappings, roles and permissions
+-- name: GetUserPermissionNames :many
+SELECT DISTINCT
+    p.name
+FROM
+    user_roles ur
+JOIN
+    roles r ON r.role_id = ur.role_id
+JOIN
+    role_permissions rp ON rp.role_id = ur.role_id
+JOIN
+    permissions p ON p.permission_id = rp.permission_id
+WHERE
+    ur.user_id = $1
+    AND ur.deleted_at IS NULL
+    AND r.deleted_at IS NULL
+    AND p.deleted_at IS NULL
+ORDER BY
+    p.name ASC;
@@ -12,7 +12,7 @@
 -- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens (user_id, token, expiration, created_at, updated_at)
 VALUES ($1, $2, $3, current_timestamp, current_timestamp)
-RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at;
+RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at;
 
 -- FindRefreshTokenByToken: Retrieves active refresh token by token string
 -- Purpose: Validate and lookup refresh token
@@ -24,7 +24,7 @@ RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at,
 --   - Used during token refresh operations
 --   - Helps prevent token reuse
 -- name: FindRefreshTokenByToken :one
-SELECT refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at
+SELECT refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
 FROM refresh_tokens
 WHERE token = $1 AND deleted_at IS NULL;
 
@@ -38,6 +38,10 @@ WHERE token = $1 AND deleted_at IS NULL;
 --   - Orders by creation date (newest first)
 --   - Used for token management and validation
 --   - Limits to 1 result to get latest token
+--   - Skips tokens already exchanged by rotation
+--
+-- Deprecated: users hold one token family per session, so the newest token
+-- is not necessarily the one presented; use FindRefreshTokenByHash.
 -- name: FindRefreshTokenByUserId :one
 SELECT
     refresh_token_id,
@@ -46,11 +50,13 @@ SELECT
     expiration,
     created_at,
     updated_at,
-    deleted_at
+    deleted_at,
+    family_id,
+    rotated_at
 FROM
     refresh_tokens
 WHERE
-    user_id = $1 AND deleted_at IS NULL
+    user_id = $1 AND deleted_at IS NULL AND rotated_at IS NULL
 ORDER BY
     created_at DESC
 LIMIT 1;
@@ -64,12 +70,21 @@ LIMIT 1;
 -- Business Logic:
 --   - Updates token and expiration fields
 --   - Sets updated_at to current time
---   - Only modifies active tokens
+--   - Only modifies the most recent active token, never other sessions
 --   - Used during token rotation flows
+--
+-- Deprecated: overwriting a token in place defeats reuse detection; use
+-- ExchangeRefreshToken.
 -- name: UpdateRefreshTokenByUserId :one
 UPDATE refresh_tokens
 SET token = $2, expiration = $3, updated_at = current_timestamp
-WHERE user_id = $1 AND deleted_at IS NULL
+WHERE refresh_token_id = (
+    SELECT latest.refresh_token_id
+    FROM refresh_tokens AS latest
+    WHERE latest.user_id = $1 AND latest.deleted_at IS NULL AND latest.rotated_at IS NULL
+    ORDER BY latest.created_at DESC
+    LIMIT 1
+)
 RETURNING *;
 
 -- DeleteRefreshToken: Permanently deletes a refresh token
@@ -95,3 +110,103 @@ WHERE token = $1;
 -- name: DeleteRefreshTokenByUserId :exec
 DELETE FROM refresh_tokens
 WHERE user_id = $1;
+
+-- CreateRefreshTokenInFamily: Creates a refresh token belonging to a token family
+-- Purpose: Issue the first token of a login session or the successor of a rotated token
+-- Parameters:
+--   $1: user_id - ID of the user this token belongs to
+--   $2: token - SHA-256 hash of the opaque refresh token
+--   $3: expiration - Expiration timestamp of the token
+--   $4: family_id - Identifier shared by every token of the same session
+-- Returns: The created refresh token record
+-- Business Logic:
+--   - Only the hash is stored, the plaintext token is returned to the client once
+--   - Several families may exist per user (one per device/session)
+-- name: CreateRefreshTokenInFamily :one
+INSERT INTO refresh_tokens (user_id, token, expiration, family_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4, current_timestamp, current_timestamp)
+RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at;
+
+-- FindRefreshTokenByHash: Retrieves a refresh token by hash regardless of state
+-- Purpose: Lookup used by rotation to tell valid, rotated and revoked tokens apart
+-- Parameters:
+--   $1: token - SHA-256 hash of the presented refresh token
+-- Returns: The refresh token record including rotated and revoked tokens
+-- Business Logic:
+--   - Does not filter on deleted_at or rotated_at
+--   - A hit on an already rotated token indicates token reuse
+-- name: FindRefreshTokenByHash :one
+SELECT refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
+FROM refresh_tokens
+WHERE token = $1;
+
+-- ExchangeRefreshToken: Consumes a refresh token and stores its successor
+-- Purpose: Refresh token rotation
+-- Parameters:
+--   $1: refresh_token_id - ID of the token being exchanged
+--   $2: token - SHA-256 hash of the successor token
+--   $3: expiration - Expiration timestamp of the successor
+-- Returns: The successor, in the family of the exchanged token
+-- Business Logic:
+--   - Marks the old token and inserts the new one in a single statement, so
+--     either both happen or neither does
+--   - Returns no rows if the token was already rotated or revoked
+--   - Keeps the old row so later replays can be detected
+-- name: ExchangeRefreshToken :one
+WITH rotated AS (
+    UPDATE refresh_tokens
+    SET rotated_at = current_timestamp, updated_at = current_timestamp
+    WHERE refresh_token_id = sqlc.arg(refresh_token_id) AND rotated_at IS NULL AND deleted_at IS NULL
+    RETURNING user_id, family_id
+)
+INSERT INTO refresh_tokens (user_id, token, expiration, family_id, created_at, updated_at)
+SELECT user_id, sqlc.arg(token)::VARCHAR, sqlc.arg(expiration)::TIMESTAMP, family_id, current_timestamp, current_timestamp
+FROM rotated
+RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at;
+
+-- RevokeRefreshTokenFamily: Revokes every token of a token family
+-- Purpose: End a session, or contain a detected refresh token replay
+-- Parameters:
+--   $1: family_id - Token family to revoke
+-- Business Logic:
+--   - Soft deletes all active tokens sharing the family
+--   - Rows are kept so replays keep being reported as revoked
+--   - Never matches the empty family
+--   - Marks the session of the family as revoked too
+-- name: RevokeRefreshTokenFamily :exec
+WITH revoked AS (
+    UPDATE refresh_tokens
+    SET deleted_at = current_timestamp, updated_at = current_timestamp
+    WHERE family_id = $1 AND family_id <> '' AND deleted_at IS NULL
+)
+UPDATE user_sessions
+SET revoked_at = current_timestamp
+WHERE session_id = $1 AND session_id <> '' AND revoked_at IS NULL;
+
+-- RevokeRefreshTokensByUserId: Revokes every token family of a user
+-- Purpose: Logout from all devices
+-- Parameters:
+--   $1: user_id - ID of the user whose sessions are revoked
+-- Business Logic:
+--   - Soft deletes all active tokens of the user
+--   - Used after password reset, ban or explicit logout-everywhere
+--   - Marks every session of the user as revoked too
+-- name: RevokeRefreshTokensByUserId :exec
+WITH revoked AS (
+    UPDATE refresh_tokens
+    SET deleted_at = current_timestamp, updated_at = current_timestamp
+    WHERE user_id = $1 AND deleted_at IS NULL
+)
+UPDATE user_sessions
+SET revoked_at = current_timestamp
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- DeleteExpiredRefreshTokens: Purges refresh tokens past their expiration
+-- Purpose: Housekeeping of rotated and revoked rows kept for reuse detection
+-- Parameters:
+--   $1: expiration - Tokens expiring before this timestamp are removed
+-- Business Logic:
+--   - Hard deletes rows; an expired token is rejected regardless of reuse state
+-- name: DeleteExpiredRefreshTokens :exec
+DELETE FROM refresh_tokens
+WHERE expiration < $1;
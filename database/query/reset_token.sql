@@ -9,4 +9,15 @@ RETURNING
 DELETE FROM reset_tokens WHERE user_id = $1;
 
 -- name: GetResetToken :one
-SELECT * FROM reset_tokens WHERE token = $1;
\ No newline at end of file
+SELECT * FROM reset_tokens WHERE token = $1;
+
+-- ConsumeResetToken: Deletes a reset token and returns it
+-- Purpose: Single-use redemption of a password reset token
+-- Parameters:
+--   $1: token - Hash of the reset token
+-- Returns: The deleted token, or no rows if it was already used
+-- Business Logic:
+--   - Lookup and delete happen in one statement so concurrent requests
+--     cannot redeem the same token twice
+-- name: ConsumeResetToken :one
+DELETE FROM reset_tokens WHERE token = $1 RETURNING *;
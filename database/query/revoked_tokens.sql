@@ -0,0 +1,56 @@
+-- RevokeToken: Adds an access token to the revocation list
+-- Purpose: Invalidate a single access token before its natural expiry
+-- Parameters:
+--   $1: jti - Unique ID of the access token
+--   $2: user_id - Owner of the token
+--   $3: expires_at - Expiry of the token, after which the entry can be purged
+-- Business Logic:
+--   - Idempotent; revoking the same jti twice keeps the first entry
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at)
+VALUES ($1, $2, $3, current_timestamp)
+ON CONFLICT (jti) DO NOTHING;
+
+-- IsTokenRevoked: Checks whether an access token has been revoked
+-- Purpose: Consulted while validating access tokens
+-- Parameters:
+--   $1: jti - Unique ID of the access token
+-- Returns: true when the jti is on the revocation list
+-- name: IsTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM revoked_tokens WHERE jti = $1
+);
+
+-- RevokeUserTokensBefore: Revokes every access token of a user issued before a cut-off
+-- Purpose: Logout everywhere, password reset and account bans
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: revoked_before - Tokens issued before this timestamp are rejected
+-- Business Logic:
+--   - One row per user; the cut-off only ever moves forward
+-- name: RevokeUserTokensBefore :exec
+INSERT INTO user_token_revocations (user_id, revoked_before, updated_at)
+VALUES ($1, $2, current_timestamp)
+ON CONFLICT (user_id) DO UPDATE
+SET revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before),
+    updated_at = current_timestamp;
+
+-- GetUserTokensRevokedBefore: Retrieves the token cut-off of a user
+-- Purpose: Consulted while validating access tokens
+-- Parameters:
+--   $1: user_id - ID of the user
+-- Returns: The cut-off timestamp, no rows when the user never revoked all tokens
+-- name: GetUserTokensRevokedBefore :one
+SELECT revoked_before
+FROM user_token_revocations
+WHERE user_id = $1;
+
+-- DeleteExpiredRevokedTokens: Purges revocation entries of expired tokens
+-- Purpose: Keep the revocation list small
+-- Parameters:
+--   $1: expires_at - Entries for tokens expiring before this timestamp are removed
+-- Business Logic:
+--   - Expired tokens are rejected anyway, their entries are no longer needed
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expires_at < $1;
@@ -0,0 +1,39 @@
+-- GetUserIdentity: Retrieves a linked external identity
+-- Purpose: Resolve the local user of a social login
+-- Parameters:
+--   $1: provider - Name of the identity provider, e.g. "google"
+--   $2: subject - The provider's stable user identifier ("sub")
+-- Returns: The identity record, if linked
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities WHERE provider = $1 AND subject = $2;
+
+-- CreateUserIdentity: Links an external identity to a user
+-- Purpose: Remember a social login for later sign-ins
+-- Parameters:
+--   $1: user_id - ID of the local user
+--   $2: provider - Name of the identity provider
+--   $3: subject - The provider's stable user identifier
+--   $4: email - Email reported by the provider at link time
+-- Returns: The created identity record
+-- Business Logic:
+--   - A provider subject can only be linked to one user
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email, created_at, updated_at)
+VALUES ($1, $2, $3, $4, current_timestamp, current_timestamp)
+RETURNING *;
+
+-- GetUserIdentitiesByUserID: Lists the external identities of a user
+-- Purpose: Show and manage connected accounts
+-- Parameters:
+--   $1: user_id - ID of the local user
+-- Returns: All identities linked to the user
+-- name: GetUserIdentitiesByUserID :many
+SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_at;
+
+-- DeleteUserIdentity: Unlinks an external identity
+-- Purpose: Disconnect a social account
+-- Parameters:
+--   $1: user_id - ID of the local user
+--   $2: provider - Name of the identity provider
+-- name: DeleteUserIdentity :exec
+DELETE FROM user_identities WHERE user_id = $1 AND provider = $2;
@@ -0,0 +1,84 @@
+-- CreateUserSession: Records a new login session
+-- Purpose: Track the device behind a refresh token family
+-- Parameters:
+--   $1: session_id - Refresh token family ID
+--   $2: user_id - ID of the user
+--   $3: user_agent - User-Agent header of the login request
+--   $4: ip_address - Client IP of the login request
+--   $5: device_label - Human readable device, e.g. "Chrome on Windows"
+-- Returns: The created session
+-- name: CreateUserSession :one
+INSERT INTO user_sessions (session_id, user_id, user_agent, ip_address, device_label, created_at, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, current_timestamp, current_timestamp)
+RETURNING *;
+
+-- GetUserSession: Retrieves a session by ID
+-- Purpose: Ownership checks before revoking
+-- Parameters:
+--   $1: session_id - Refresh token family ID
+-- Returns: The session, revoked or not
+-- name: GetUserSession :one
+SELECT * FROM user_sessions WHERE session_id = $1;
+
+-- GetActiveUserSessions: Lists the sessions a user is logged in with
+-- Purpose: "Where you're logged in" page
+-- Parameters:
+--   $1: user_id - ID of the user
+-- Returns: Sessions not yet revoked, most recently used first
+-- Business Logic:
+--   - Leaves out sessions whose refresh tokens all expired or were revoked
+-- name: GetActiveUserSessions :many
+SELECT user_sessions.* FROM user_sessions
+WHERE user_sessions.user_id = $1
+  AND user_sessions.revoked_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM refresh_tokens
+      WHERE refresh_tokens.family_id = user_sessions.session_id
+        AND refresh_tokens.deleted_at IS NULL
+        AND refresh_tokens.rotated_at IS NULL
+        AND refresh_tokens.expiration > current_timestamp
+  )
+ORDER BY user_sessions.last_seen_at DESC;
+
+-- HasUserSessionOnDevice: Checks whether a user logged in from a device before
+-- Purpose: Detect new-device logins
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: device_label - Device label of the new login
+-- Returns: true when any earlier session used the same device label
+-- name: HasUserSessionOnDevice :one
+SELECT EXISTS (
+    SELECT 1 FROM user_sessions WHERE user_id = $1 AND device_label = $2
+);
+
+-- TouchUserSession: Updates the last activity of a session
+-- Purpose: Called on every refresh token rotation
+-- Parameters:
+--   $1: session_id - Refresh token family ID
+--   $2: ip_address - Client IP of the refresh request
+-- name: TouchUserSession :exec
+UPDATE user_sessions
+SET last_seen_at = current_timestamp,
+    ip_address = $2
+WHERE session_id = $1 AND revoked_at IS NULL;
+
+-- RevokeUserSession: Marks a session as logged out
+-- Purpose: Sign out a single device
+-- Parameters:
+--   $1: session_id - Refresh token family ID
+-- name: RevokeUserSession :exec
+UPDATE user_sessions
+SET revoked_at = current_timestamp
+WHERE session_id = $1 AND revoked_at IS NULL;
+
+-- RevokeOtherUserSessions: Marks all but one session of a user as logged out
+-- Purpose: "Sign out everywhere else"
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: session_id - Session to keep
+-- Returns: IDs of the revoked sessions, so their refresh tokens can be revoked
+-- name: RevokeOtherUserSessions :many
+UPDATE user_sessions
+SET revoked_at = current_timestamp
+WHERE user_id = $1 AND session_id <> $2 AND revoked_at IS NULL
+RETURNING session_id;
@@ -0,0 +1,99 @@
+-- UpsertUserTotp: Stores a new TOTP secret for a user
+-- Purpose: Start (or restart) two-factor enrolment
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: secret - TOTP secret, AES-GCM encrypted by the application
+-- Returns: The stored TOTP record
+-- Business Logic:
+--   - The secret stays disabled until the first code is confirmed
+--   - Re-enrolling resets the replay counter
+-- name: UpsertUserTotp :one
+INSERT INTO user_totp (user_id, secret, enabled, last_used_step, created_at, updated_at)
+VALUES ($1, $2, false, 0, current_timestamp, current_timestamp)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    enabled = false,
+    last_used_step = 0,
+    updated_at = current_timestamp
+RETURNING *;
+
+-- GetUserTotp: Retrieves the TOTP settings of a user
+-- Purpose: Verify codes and check whether two-factor is enabled
+-- Parameters:
+--   $1: user_id - ID of the user
+-- Returns: The TOTP record, if the user enrolled
+-- name: GetUserTotp :one
+SELECT * FROM user_totp WHERE user_id = $1;
+
+-- EnableUserTotp: Turns on two-factor authentication
+-- Purpose: Finish enrolment after the first valid code
+-- Parameters:
+--   $1: user_id - ID of the user
+-- name: EnableUserTotp :exec
+UPDATE user_totp
+SET enabled = true,
+    updated_at = current_timestamp
+WHERE user_id = $1;
+
+-- MarkTotpStepUsed: Records the time step of an accepted code
+-- Purpose: Replay protection for TOTP codes
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: last_used_step - Time step the accepted code belongs to
+-- Returns: The user ID, or no rows if the step was already used
+-- Business Logic:
+--   - Only moves forward, so each code is accepted at most once even
+--     under concurrent requests
+-- name: MarkTotpStepUsed :one
+UPDATE user_totp
+SET last_used_step = $2,
+    updated_at = current_timestamp
+WHERE user_id = $1
+  AND last_used_step < $2
+RETURNING user_id;
+
+-- DeleteUserTotp: Removes the TOTP secret of a user
+-- Purpose: Disable two-factor authentication
+-- Parameters:
+--   $1: user_id - ID of the user
+-- name: DeleteUserTotp :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- CreateRecoveryCode: Stores a hashed one-time recovery code
+-- Purpose: Fallback second factor when the device is lost
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: code_hash - SHA-256 hash of the recovery code
+-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (user_id, code_hash, created_at)
+VALUES ($1, $2, current_timestamp);
+
+-- DeleteRecoveryCodes: Removes all recovery codes of a user
+-- Purpose: Regenerate codes or disable two-factor authentication
+-- Parameters:
+--   $1: user_id - ID of the user
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes WHERE user_id = $1;
+
+-- UseRecoveryCode: Marks a recovery code as used
+-- Purpose: Redeem a recovery code exactly once
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: code_hash - SHA-256 hash of the submitted code
+-- Returns: The recovery code ID, or no rows if unknown or already used
+-- name: UseRecoveryCode :one
+UPDATE user_recovery_codes
+SET used_at = current_timestamp
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL
+RETURNING recovery_code_id;
+
+-- CountUnusedRecoveryCodes: Counts the recovery codes a user has left
+-- Purpose: Prompt users to regenerate codes before they run out
+-- Parameters:
+--   $1: user_id - ID of the user
+-- Returns: Number of unused recovery codes
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM user_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
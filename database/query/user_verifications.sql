@@ -0,0 +1,76 @@
+-- UpsertUserVerification: Stores a new verification code for a user
+-- Purpose: Issue or resend an email verification code
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: code - HMAC of the code or link token
+--   $3: expires_at - When the code stops being accepted
+--   $4: last_sent_at - Send time, used for the resend cooldown
+-- Returns: The stored verification record
+-- Business Logic:
+--   - One pending code per user; a resend replaces the previous code
+--   - Resets the attempts counter and records the send time for the cooldown
+-- name: UpsertUserVerification :one
+INSERT INTO user_verifications (user_id, code, expires_at, attempts, last_sent_at, created_at)
+VALUES ($1, $2, $3, 0, $4, current_timestamp)
+ON CONFLICT (user_id) DO UPDATE
+SET code = EXCLUDED.code,
+    expires_at = EXCLUDED.expires_at,
+    attempts = 0,
+    last_sent_at = EXCLUDED.last_sent_at
+RETURNING *;
+
+-- GetUserVerification: Retrieves the pending verification of a user
+-- Purpose: Check expiry, attempts and resend cooldown
+-- Parameters:
+--   $1: user_id - ID of the user
+-- Returns: The pending verification record, if any
+-- name: GetUserVerification :one
+SELECT * FROM user_verifications WHERE user_id = $1;
+
+-- GetUserVerificationByCode: Retrieves a pending verification by code HMAC
+-- Purpose: Resolve the user of a verification link
+-- Parameters:
+--   $1: code - HMAC of the link token
+-- Returns: The pending verification record, if any
+-- name: GetUserVerificationByCode :one
+SELECT * FROM user_verifications WHERE code = $1;
+
+-- IncrementUserVerificationAttempts: Records a failed verification attempt
+-- Purpose: Limit guessing of short numeric codes
+-- Parameters:
+--   $1: user_id - ID of the user
+-- Returns: The number of failed attempts so far
+-- name: IncrementUserVerificationAttempts :one
+UPDATE user_verifications
+SET attempts = attempts + 1
+WHERE user_id = $1
+RETURNING attempts;
+
+-- VerifyUserWithCode: Consumes a verification code and marks the user verified
+-- Purpose: Complete email verification
+-- Parameters:
+--   $1: user_id - ID of the user
+--   $2: code - HMAC of the submitted code
+--   $3: attempts - Maximum number of failed attempts allowed
+-- Returns: The verified user record, or no rows if the code was not accepted
+-- Business Logic:
+--   - Code lookup, attempt check, deletion and the user update
+--     run in one statement so a code can only be used once
+--   - Clears the legacy users.verification_code column
+-- name: VerifyUserWithCode :one
+WITH consumed AS (
+    DELETE FROM user_verifications
+    WHERE user_verifications.user_id = $1
+      AND user_verifications.code = $2
+      AND user_verifications.attempts < $3
+    RETURNING user_verifications.user_id
+)
+UPDATE users
+SET
+    is_verified = true,
+    verification_code = '',
+    updated_at = current_timestamp
+FROM consumed
+WHERE users.user_id = consumed.user_id
+  AND users.deleted_at IS NULL
+RETURNING users.*;
@@ -102,15 +102,16 @@ WHERE user_id = $1 AND deleted_at IS NULL AND is_verified = true;
 -- GetUserByEmail: Retrieves active user by email
 -- Purpose: Lookup user by email address (for authentication)
 -- Parameters:
---   $1: email - Exact email address to search for
+--   $1: email - Email address to search for, in any case
 -- Returns: User record if found and active
 -- Business Logic:
---   - Case-sensitive exact match on email
+--   - Case-insensitive match on email, so addresses typed with a
+--     different case still find the account
 --   - Excludes deleted users
 --   - Used during login/authentication flows
 --   - Helps prevent duplicate accounts
 -- name: GetUserByEmail :one
-SELECT * FROM users WHERE email = $1 AND deleted_at IS NULL;
+SELECT * FROM users WHERE lower(email) = lower(sqlc.arg(email)::VARCHAR) AND deleted_at IS NULL;
 
 
 
@@ -98,3 +98,27 @@ WHERE
     AND ur.deleted_at IS NOT NULL
 ORDER BY 
     ur.deleted_at DESC;
+
+
+-- GetUserRoleNames: Retrieves the names of all active roles assigned to a user
+-- Purpose: Populate role claims when issuing access tokens
+-- Parameters:
+--   $1: User ID
+-- Returns:
+--   List of role names
+-- Business Logic:
+--   - Ignores trashed user-role mappings and trashed roles
+--   - Orders alphabetically for stable token contents
+-- name: GetUserRoleNames :many
+SELECT 
+    r.role_name
+FROM 
+    user_roles ur
+JOIN 
+    roles r ON ur.role_id = r.role_id
+WHERE 
+    ur.user_id = $1
+    AND ur.deleted_at IS NULL
+    AND r.deleted_at IS NULL
+ORDER BY 
+    r.role_name ASC;
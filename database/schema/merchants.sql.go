@@ -145,6 +145,47 @@ func (q *Queries) GetMerchantByID(ctx context.Context, merchantID int32) (*Merch
 	return &i, err
 }
 
+const getMerchantIDsByUserID = `-- name: GetMerchantIDsByUserID :many
+SELECT merchant_id
+FROM merchants
+WHERE user_id = $1
+  AND deleted_at IS NULL
+ORDER BY merchant_id
+`
+
+// GetMerchantIDsByUserID: Retrieves IDs of active merchants owned by a user
+// Purpose: Populate ownership claims when issuing access tokens
+// Parameters:
+//
+//	$1: user_id - Owner user ID
+//
+// Returns: List of merchant IDs
+// Business Logic:
+//   - Excludes soft-deleted records
+//   - Orders by merchant_id for stable token contents
+func (q *Queries) GetMerchantIDsByUserID(ctx context.Context, userID int32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, getMerchantIDsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var merchant_id int32
+		if err := rows.Scan(&merchant_id); err != nil {
+			return nil, err
+		}
+		items = append(items, merchant_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getMerchants = `-- name: GetMerchants :many
 SELECT
     merchant_id, user_id, name, description, address, contact_email, contact_phone, status, created_at, updated_at, deleted_at,
@@ -47,6 +47,13 @@ type Category struct {
 	DeletedAt     sql.NullTime   `json:"deleted_at"`
 }
 
+type LoginAttempt struct {
+	AttemptKey    string       `json:"attempt_key"`
+	Failures      int32        `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+}
+
 type Merchant struct {
 	MerchantID   int32          `json:"merchant_id"`
 	UserID       int32          `json:"user_id"`
@@ -156,6 +163,15 @@ type OrderItem struct {
 	DeletedAt   sql.NullTime `json:"deleted_at"`
 }
 
+type Permission struct {
+	PermissionID int32          `json:"permission_id"`
+	Name         string         `json:"name"`
+	Description  sql.NullString `json:"description"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
+	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	DeletedAt    sql.NullTime   `json:"deleted_at"`
+}
+
 type Product struct {
 	ProductID    int32           `json:"product_id"`
 	MerchantID   int32           `json:"merchant_id"`
@@ -182,11 +198,13 @@ type RefreshToken struct {
 	CreatedAt      sql.NullTime `json:"created_at"`
 	UpdatedAt      sql.NullTime `json:"updated_at"`
 	DeletedAt      sql.NullTime `json:"deleted_at"`
+	FamilyID       string       `json:"family_id"`
+	RotatedAt      sql.NullTime `json:"rotated_at"`
 }
 
 type ResetToken struct {
 	ID         int32     `json:"id"`
-	UserID     int64     `json:"user_id"`
+	UserID     int32     `json:"user_id"`
 	Token      string    `json:"token"`
 	ExpiryDate time.Time `json:"expiry_date"`
 }
@@ -214,6 +232,13 @@ type ReviewDetail struct {
 	DeletedAt      sql.NullTime   `json:"deleted_at"`
 }
 
+type RevokedToken struct {
+	Jti       string       `json:"jti"`
+	UserID    int32        `json:"user_id"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	RevokedAt sql.NullTime `json:"revoked_at"`
+}
+
 type Role struct {
 	RoleID    int32        `json:"role_id"`
 	RoleName  string       `json:"role_name"`
@@ -222,6 +247,14 @@ type Role struct {
 	DeletedAt sql.NullTime `json:"deleted_at"`
 }
 
+type RolePermission struct {
+	RolePermissionID int32        `json:"role_permission_id"`
+	RoleID           int32        `json:"role_id"`
+	PermissionID     int32        `json:"permission_id"`
+	CreatedAt        sql.NullTime `json:"created_at"`
+	UpdatedAt        sql.NullTime `json:"updated_at"`
+}
+
 type ShippingAddress struct {
 	ShippingAddressID int32        `json:"shipping_address_id"`
 	OrderID           int32        `json:"order_id"`
@@ -271,6 +304,24 @@ type User struct {
 	DeletedAt        sql.NullTime `json:"deleted_at"`
 }
 
+type UserIdentity struct {
+	UserIdentityID int32        `json:"user_identity_id"`
+	UserID         int32        `json:"user_id"`
+	Provider       string       `json:"provider"`
+	Subject        string       `json:"subject"`
+	Email          string       `json:"email"`
+	CreatedAt      sql.NullTime `json:"created_at"`
+	UpdatedAt      sql.NullTime `json:"updated_at"`
+}
+
+type UserRecoveryCode struct {
+	RecoveryCodeID int32        `json:"recovery_code_id"`
+	UserID         int32        `json:"user_id"`
+	CodeHash       string       `json:"code_hash"`
+	UsedAt         sql.NullTime `json:"used_at"`
+	CreatedAt      sql.NullTime `json:"created_at"`
+}
+
 type UserRole struct {
 	UserRoleID int32        `json:"user_role_id"`
 	UserID     int32        `json:"user_id"`
@@ -279,3 +330,38 @@ type UserRole struct {
 	UpdatedAt  sql.NullTime `json:"updated_at"`
 	DeletedAt  sql.NullTime `json:"deleted_at"`
 }
+
+type UserSession struct {
+	SessionID   string       `json:"session_id"`
+	UserID      int32        `json:"user_id"`
+	UserAgent   string       `json:"user_agent"`
+	IpAddress   string       `json:"ip_address"`
+	DeviceLabel string       `json:"device_label"`
+	CreatedAt   time.Time    `json:"created_at"`
+	LastSeenAt  time.Time    `json:"last_seen_at"`
+	RevokedAt   sql.NullTime `json:"revoked_at"`
+}
+
+type UserTokenRevocation struct {
+	UserID        int32        `json:"user_id"`
+	RevokedBefore time.Time    `json:"revoked_before"`
+	UpdatedAt     sql.NullTime `json:"updated_at"`
+}
+
+type UserTotp struct {
+	UserID       int32        `json:"user_id"`
+	Secret       string       `json:"secret"`
+	Enabled      bool         `json:"enabled"`
+	LastUsedStep int64        `json:"last_used_step"`
+	CreatedAt    sql.NullTime `json:"created_at"`
+	UpdatedAt    sql.NullTime `json:"updated_at"`
+}
+
+type UserVerification struct {
+	UserID     int32        `json:"user_id"`
+	Code       string       `json:"code"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	Attempts   int32        `json:"attempts"`
+	LastSentAt time.Time    `json:"last_sent_at"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+}
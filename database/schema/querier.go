@@ -10,6 +10,16 @@ import (
 )
 
 type Querier interface {
+	// AssignPermissionToRole: Grants a permission to a role
+	// Purpose: Build the role -> permission mapping
+	// Parameters:
+	//   $1: Role ID
+	//   $2: Permission ID
+	// Returns:
+	//   The role-permission mapping
+	// Business Logic:
+	//   - Idempotent; granting an existing mapping only refreshes updated_at
+	AssignPermissionToRole(ctx context.Context, arg AssignPermissionToRoleParams) (*RolePermission, error)
 	// AssignRoleToUser: Assigns a role to a user (creates a user-role relation)
 	// Purpose: Role management for user access control
 	// Parameters:
@@ -31,6 +41,21 @@ type Querier interface {
 	//   - Ignores soft-deleted items
 	//   - Ensures result is zero if no items exist
 	CalculateTotalPrice(ctx context.Context, orderID int32) (int32, error)
+	// ConsumeResetToken: Deletes a reset token and returns it
+	// Purpose: Single-use redemption of a password reset token
+	// Parameters:
+	//   $1: token - Hash of the reset token
+	// Returns: The deleted token, or no rows if it was already used
+	// Business Logic:
+	//   - Lookup and delete happen in one statement so concurrent requests
+	//     cannot redeem the same token twice
+	ConsumeResetToken(ctx context.Context, token string) (*ResetToken, error)
+	// CountUnusedRecoveryCodes: Counts the recovery codes a user has left
+	// Purpose: Prompt users to regenerate codes before they run out
+	// Parameters:
+	//   $1: user_id - ID of the user
+	// Returns: Number of unused recovery codes
+	CountUnusedRecoveryCodes(ctx context.Context, userID int32) (int64, error)
 	// CreateBanner: Inserts a new banner
 	// Parameters:
 	//   $1: name
@@ -172,6 +197,14 @@ type Querier interface {
 	// Business Logic:
 	//   - Assumes quantity and price are validated in application layer
 	CreateOrderItem(ctx context.Context, arg CreateOrderItemParams) (*OrderItem, error)
+	// CreatePermission: Inserts a new permission
+	// Purpose: Define a permission in resource:action[:scope] form (e.g. product:write:own)
+	// Parameters:
+	//   $1: Permission name
+	//   $2: Description (nullable)
+	// Returns:
+	//   Newly created permission record
+	CreatePermission(ctx context.Context, arg CreatePermissionParams) (*Permission, error)
 	// CreateProduct: Creates a new product entry
 	// Purpose: Add new products to merchant's catalog
 	// Parameters:
@@ -193,6 +226,12 @@ type Querier interface {
 	//   - Requires all essential product information
 	//   - Returns full record for immediate use
 	CreateProduct(ctx context.Context, arg CreateProductParams) (*Product, error)
+	// CreateRecoveryCode: Stores a hashed one-time recovery code
+	// Purpose: Fallback second factor when the device is lost
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: code_hash - SHA-256 hash of the recovery code
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
 	// CreateRefreshToken: Creates a new refresh token
 	// Purpose: Generate a refresh token for user authentication
 	// Parameters:
@@ -205,6 +244,18 @@ type Querier interface {
 	//   - Used in JWT refresh token rotation
 	//   - Typically created during login/auth flows
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (*RefreshToken, error)
+	// CreateRefreshTokenInFamily: Creates a refresh token belonging to a token family
+	// Purpose: Issue the first token of a login session or the successor of a rotated token
+	// Parameters:
+	//   $1: user_id - ID of the user this token belongs to
+	//   $2: token - SHA-256 hash of the opaque refresh token
+	//   $3: expiration - Expiration timestamp of the token
+	//   $4: family_id - Identifier shared by every token of the same session
+	// Returns: The created refresh token record
+	// Business Logic:
+	//   - Only the hash is stored, the plaintext token is returned to the client once
+	//   - Several families may exist per user (one per device/session)
+	CreateRefreshTokenInFamily(ctx context.Context, arg CreateRefreshTokenInFamilyParams) (*RefreshToken, error)
 	CreateResetToken(ctx context.Context, arg CreateResetTokenParams) (*ResetToken, error)
 	// CreateReview: Creates a new product review
 	// Purpose: Allow users to submit product reviews
@@ -297,6 +348,27 @@ type Querier interface {
 	//   - Email must be unique across the system
 	//   - Password should be pre-hashed before insertion
 	CreateUser(ctx context.Context, arg CreateUserParams) (*User, error)
+	// CreateUserIdentity: Links an external identity to a user
+	// Purpose: Remember a social login for later sign-ins
+	// Parameters:
+	//   $1: user_id - ID of the local user
+	//   $2: provider - Name of the identity provider
+	//   $3: subject - The provider's stable user identifier
+	//   $4: email - Email reported by the provider at link time
+	// Returns: The created identity record
+	// Business Logic:
+	//   - A provider subject can only be linked to one user
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (*UserIdentity, error)
+	// CreateUserSession: Records a new login session
+	// Purpose: Track the device behind a refresh token family
+	// Parameters:
+	//   $1: session_id - Refresh token family ID
+	//   $2: user_id - ID of the user
+	//   $3: user_agent - User-Agent header of the login request
+	//   $4: ip_address - Client IP of the login request
+	//   $5: device_label - Human readable device, e.g. "Chrome on Windows"
+	// Returns: The created session
+	CreateUserSession(ctx context.Context, arg CreateUserSessionParams) (*UserSession, error)
 	// DeleteAllCartByUserId: Deletes multiple cart items for a specific user
 	// Purpose: Delete selected cart items securely
 	// Parameters:
@@ -460,6 +532,25 @@ type Querier interface {
 	// Business Logic:
 	//   - Ensures category is deleted only if it has been soft-deleted
 	DeleteCategoryPermanently(ctx context.Context, categoryID int32) error
+	// DeleteExpiredRefreshTokens: Purges refresh tokens past their expiration
+	// Purpose: Housekeeping of rotated and revoked rows kept for reuse detection
+	// Parameters:
+	//   $1: expiration - Tokens expiring before this timestamp are removed
+	// Business Logic:
+	//   - Hard deletes rows; an expired token is rejected regardless of reuse state
+	DeleteExpiredRefreshTokens(ctx context.Context, expiration time.Time) error
+	// DeleteExpiredRevokedTokens: Purges revocation entries of expired tokens
+	// Purpose: Keep the revocation list small
+	// Parameters:
+	//   $1: expires_at - Entries for tokens expiring before this timestamp are removed
+	// Business Logic:
+	//   - Expired tokens are rejected anyway, their entries are no longer needed
+	DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) error
+	// DeleteLoginAttempt: Clears the failed login state of a throttling key
+	// Purpose: Reset after a successful login or an unlock
+	// Parameters:
+	//   $1: attempt_key - Throttling key
+	DeleteLoginAttempt(ctx context.Context, attemptKey string) error
 	// DeleteMerchantBusinessInformationPermanently: Hard-deletes a single record
 	// Purpose: Completely remove soft-deleted business info
 	// Parameters:
@@ -546,6 +637,11 @@ type Querier interface {
 	//   - Only works on already-trashed products
 	//   - Irreversible operation
 	DeleteProductPermanently(ctx context.Context, productID int32) error
+	// DeleteRecoveryCodes: Removes all recovery codes of a user
+	// Purpose: Regenerate codes or disable two-factor authentication
+	// Parameters:
+	//   $1: user_id - ID of the user
+	DeleteRecoveryCodes(ctx context.Context, userID int32) error
 	// DeleteRefreshToken: Permanently deletes a refresh token
 	// Purpose: Invalidate a specific refresh token
 	// Parameters:
@@ -564,7 +660,7 @@ type Querier interface {
 	//   - Used during password reset or account lock
 	//   - Ensures complete session invalidation
 	DeleteRefreshTokenByUserId(ctx context.Context, userID int32) error
-	DeleteResetToken(ctx context.Context, userID int64) error
+	DeleteResetToken(ctx context.Context, userID int32) error
 	// DeleteReviewPermanently: Removes a review from database
 	// Purpose: Permanent deletion of trashed reviews
 	// Parameters:
@@ -604,6 +700,11 @@ type Querier interface {
 	//   - Irreversible operation
 	//   - Operasi tidak dapat dibatalkan
 	DeleteSliderPermanently(ctx context.Context, sliderID int32) error
+	// DeleteStaleLoginAttempts: Removes attempt records that no longer matter
+	// Purpose: Periodic cleanup
+	// Parameters:
+	//   $1: last_failure_at - Records whose last failure and lock ended before this time are deleted
+	DeleteStaleLoginAttempts(ctx context.Context, lastFailureAt time.Time) error
 	// DeleteTransactionPermanently: Hard-deletes a transaction
 	// Purpose: Completely remove transaction from database
 	// Parameters:
@@ -614,6 +715,12 @@ type Querier interface {
 	//   - Irreversible action - use with caution
 	//   - Should be restricted to admin users
 	DeleteTransactionPermanently(ctx context.Context, transactionID int32) error
+	// DeleteUserIdentity: Unlinks an external identity
+	// Purpose: Disconnect a social account
+	// Parameters:
+	//   $1: user_id - ID of the local user
+	//   $2: provider - Name of the identity provider
+	DeleteUserIdentity(ctx context.Context, arg DeleteUserIdentityParams) error
 	// DeleteUserPermanently: Hard-deletes a user account
 	// Purpose: Completely remove user from database
 	// Parameters:
@@ -624,6 +731,38 @@ type Querier interface {
 	//   - Irreversible action - use with caution
 	//   - Should trigger cleanup of related records
 	DeleteUserPermanently(ctx context.Context, userID int32) error
+	// DeleteUserTotp: Removes the TOTP secret of a user
+	// Purpose: Disable two-factor authentication
+	// Parameters:
+	//   $1: user_id - ID of the user
+	DeleteUserTotp(ctx context.Context, userID int32) error
+	// EnableUserTotp: Turns on two-factor authentication
+	// Purpose: Finish enrolment after the first valid code
+	// Parameters:
+	//   $1: user_id - ID of the user
+	EnableUserTotp(ctx context.Context, userID int32) error
+	// ExchangeRefreshToken: Consumes a refresh token and stores its successor
+	// Purpose: Refresh token rotation
+	// Parameters:
+	//   $1: refresh_token_id - ID of the token being exchanged
+	//   $2: token - SHA-256 hash of the successor token
+	//   $3: expiration - Expiration timestamp of the successor
+	// Returns: The successor, in the family of the exchanged token
+	// Business Logic:
+	//   - Marks the old token and inserts the new one in a single statement, so
+	//     either both happen or neither does
+	//   - Returns no rows if the token was already rotated or revoked
+	//   - Keeps the old row so later replays can be detected
+	ExchangeRefreshToken(ctx context.Context, arg ExchangeRefreshTokenParams) (*RefreshToken, error)
+	// FindRefreshTokenByHash: Retrieves a refresh token by hash regardless of state
+	// Purpose: Lookup used by rotation to tell valid, rotated and revoked tokens apart
+	// Parameters:
+	//   $1: token - SHA-256 hash of the presented refresh token
+	// Returns: The refresh token record including rotated and revoked tokens
+	// Business Logic:
+	//   - Does not filter on deleted_at or rotated_at
+	//   - A hit on an already rotated token indicates token reuse
+	FindRefreshTokenByHash(ctx context.Context, token string) (*RefreshToken, error)
 	// FindRefreshTokenByToken: Retrieves active refresh token by token string
 	// Purpose: Validate and lookup refresh token
 	// Parameters:
@@ -644,6 +783,10 @@ type Querier interface {
 	//   - Orders by creation date (newest first)
 	//   - Used for token management and validation
 	//   - Limits to 1 result to get latest token
+	//   - Skips tokens already exchanged by rotation
+	//
+	// Deprecated: users hold one token family per session, so the newest token
+	// is not necessarily the one presented; use FindRefreshTokenByHash.
 	FindRefreshTokenByUserId(ctx context.Context, userID int32) (*RefreshToken, error)
 	GetActiveMerchantDocuments(ctx context.Context, arg GetActiveMerchantDocumentsParams) ([]*GetActiveMerchantDocumentsRow, error)
 	// GetActiveRoles: Retrieves only active (non-deleted) roles with optional search and pagination
@@ -655,6 +798,14 @@ type Querier interface {
 	// Returns:
 	//   role_id, role_name, timestamps, and total_count
 	GetActiveRoles(ctx context.Context, arg GetActiveRolesParams) ([]*GetActiveRolesRow, error)
+	// GetActiveUserSessions: Lists the sessions a user is logged in with
+	// Purpose: "Where you're logged in" page
+	// Parameters:
+	//   $1: user_id - ID of the user
+	// Returns: Sessions not yet revoked, most recently used first
+	// Business Logic:
+	//   - Leaves out sessions whose refresh tokens all expired or were revoked
+	GetActiveUserSessions(ctx context.Context, userID int32) ([]*UserSession, error)
 	// GetBanner: Retrieves a single banner that is not soft-deleted
 	// Parameters:
 	//   $1: banner_id - ID of the banner
@@ -771,6 +922,12 @@ type Querier interface {
 	// Business Logic:
 	//   - Excludes soft-deleted categories
 	GetCategoryByIDTrashed(ctx context.Context, categoryID int32) (*Category, error)
+	// GetLoginAttempt: Retrieves the failed login state of a throttling key
+	// Purpose: Check whether an email or IP is currently locked
+	// Parameters:
+	//   $1: attempt_key - Throttling key, e.g. "email:<address>" or "ip:<address>"
+	// Returns: The attempt record, if any failures were recorded
+	GetLoginAttempt(ctx context.Context, attemptKey string) (*LoginAttempt, error)
 	// GetMerchantBusinessInformation: Retrieves a single business information record that is not soft-deleted
 	// Parameters:
 	//   $1: merchant_business_info_id - ID of the business info
@@ -867,6 +1024,15 @@ type Querier interface {
 	GetMerchantDetailsTrashed(ctx context.Context, arg GetMerchantDetailsTrashedParams) ([]*GetMerchantDetailsTrashedRow, error)
 	GetMerchantDocument(ctx context.Context, documentID int32) (*MerchantDocument, error)
 	GetMerchantDocuments(ctx context.Context, arg GetMerchantDocumentsParams) ([]*GetMerchantDocumentsRow, error)
+	// GetMerchantIDsByUserID: Retrieves IDs of active merchants owned by a user
+	// Purpose: Populate ownership claims when issuing access tokens
+	// Parameters:
+	//   $1: user_id - Owner user ID
+	// Returns: List of merchant IDs
+	// Business Logic:
+	//   - Excludes soft-deleted records
+	//   - Orders by merchant_id for stable token contents
+	GetMerchantIDsByUserID(ctx context.Context, userID int32) ([]int32, error)
 	// GetMerchantPolicies: Retrieves all merchant policies regardless of merchant status
 	// Parameters:
 	//   $1: search - Keyword to filter merchant_name (case-insensitive, partial match)
@@ -1408,6 +1574,18 @@ type Querier interface {
 	//   - Used in order recovery/audit interfaces
 	//   - Includes total_count for pagination in trash management UI
 	GetOrdersTrashed(ctx context.Context, arg GetOrdersTrashedParams) ([]*GetOrdersTrashedRow, error)
+	// GetPermissionByName: Retrieves an active permission by name
+	// Purpose: Lookup permission before assigning it to a role
+	// Parameters:
+	//   $1: Permission name
+	// Returns:
+	//   Permission record if found and active
+	GetPermissionByName(ctx context.Context, name string) (*Permission, error)
+	// GetPermissions: Retrieves all active permissions
+	// Purpose: List permissions for role management screens
+	// Returns:
+	//   All non-deleted permissions ordered by name
+	GetPermissions(ctx context.Context) ([]*Permission, error)
 	// GetProductByID: Retrieves an active product by ID
 	// Purpose: Display product details in storefront/merchant UI
 	// Parameters:
@@ -1668,6 +1846,13 @@ type Querier interface {
 	// Returns:
 	//   role_id, role_name, and timestamps
 	GetRoleByName(ctx context.Context, roleName string) (*Role, error)
+	// GetRolePermissions: Retrieves all active permissions granted to a role
+	// Purpose: Display the permissions of a role
+	// Parameters:
+	//   $1: Role ID
+	// Returns:
+	//   List of permission records
+	GetRolePermissions(ctx context.Context, roleID int32) ([]*Permission, error)
 	// GetRoles: Retrieves all roles (active & trashed) with optional name search and pagination
 	// Purpose: General listing of roles regardless of status
 	// Parameters:
@@ -1901,10 +2086,11 @@ type Querier interface {
 	// GetUserByEmail: Retrieves active user by email
 	// Purpose: Lookup user by email address (for authentication)
 	// Parameters:
-	//   $1: email - Exact email address to search for
+	//   $1: email - Email address to search for, in any case
 	// Returns: User record if found and active
 	// Business Logic:
-	//   - Case-sensitive exact match on email
+	//   - Case-insensitive match on email, so addresses typed with a
+	//     different case still find the account
 	//   - Excludes deleted users
 	//   - Used during login/authentication flows
 	//   - Helps prevent duplicate accounts
@@ -1945,6 +2131,38 @@ type Querier interface {
 	// Business Logic:
 	//   - Filters the users table to find a user based on their verification code.
 	GetUserByVerificationCode(ctx context.Context, verificationCode string) (*User, error)
+	// GetUserIdentitiesByUserID: Lists the external identities of a user
+	// Purpose: Show and manage connected accounts
+	// Parameters:
+	//   $1: user_id - ID of the local user
+	// Returns: All identities linked to the user
+	GetUserIdentitiesByUserID(ctx context.Context, userID int32) ([]*UserIdentity, error)
+	// GetUserIdentity: Retrieves a linked external identity
+	// Purpose: Resolve the local user of a social login
+	// Parameters:
+	//   $1: provider - Name of the identity provider, e.g. "google"
+	//   $2: subject - The provider's stable user identifier ("sub")
+	// Returns: The identity record, if linked
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (*UserIdentity, error)
+	// GetUserPermissionNames: Retrieves the distinct permission names a user holds through their roles
+	// Purpose: Input of the policy evaluator
+	// Parameters:
+	//   $1: User ID
+	// Returns:
+	//   List of permission names
+	// Business Logic:
+	//   - Ignores trashed user-role mappings, roles and permissions
+	GetUserPermissionNames(ctx context.Context, userID int32) ([]string, error)
+	// GetUserRoleNames: Retrieves the names of all active roles assigned to a user
+	// Purpose: Populate role claims when issuing access tokens
+	// Parameters:
+	//   $1: User ID
+	// Returns:
+	//   List of role names
+	// Business Logic:
+	//   - Ignores trashed user-role mappings and trashed roles
+	//   - Orders alphabetically for stable token contents
+	GetUserRoleNames(ctx context.Context, userID int32) ([]string, error)
 	// GetUserRoles: Retrieves all roles assigned to a specific user
 	// Purpose: Identify the access level(s) of a user
 	// Parameters:
@@ -1952,6 +2170,24 @@ type Querier interface {
 	// Returns:
 	//   List of roles (id, name, timestamps)
 	GetUserRoles(ctx context.Context, userID int32) ([]*Role, error)
+	// GetUserSession: Retrieves a session by ID
+	// Purpose: Ownership checks before revoking
+	// Parameters:
+	//   $1: session_id - Refresh token family ID
+	// Returns: The session, revoked or not
+	GetUserSession(ctx context.Context, sessionID string) (*UserSession, error)
+	// GetUserTokensRevokedBefore: Retrieves the token cut-off of a user
+	// Purpose: Consulted while validating access tokens
+	// Parameters:
+	//   $1: user_id - ID of the user
+	// Returns: The cut-off timestamp, no rows when the user never revoked all tokens
+	GetUserTokensRevokedBefore(ctx context.Context, userID int32) (time.Time, error)
+	// GetUserTotp: Retrieves the TOTP settings of a user
+	// Purpose: Verify codes and check whether two-factor is enabled
+	// Parameters:
+	//   $1: user_id - ID of the user
+	// Returns: The TOTP record, if the user enrolled
+	GetUserTotp(ctx context.Context, userID int32) (*UserTotp, error)
 	// GetUserTrashed: Retrieves paginated list of soft-deleted users
 	// Purpose: View and manage deleted users for potential restoration
 	// Parameters:
@@ -1967,6 +2203,18 @@ type Querier interface {
 	//   - Used in user recovery/audit interfaces
 	//   - Includes total_count for pagination in trash management UI
 	GetUserTrashed(ctx context.Context, arg GetUserTrashedParams) ([]*GetUserTrashedRow, error)
+	// GetUserVerification: Retrieves the pending verification of a user
+	// Purpose: Check expiry, attempts and resend cooldown
+	// Parameters:
+	//   $1: user_id - ID of the user
+	// Returns: The pending verification record, if any
+	GetUserVerification(ctx context.Context, userID int32) (*UserVerification, error)
+	// GetUserVerificationByCode: Retrieves a pending verification by code HMAC
+	// Purpose: Resolve the user of a verification link
+	// Parameters:
+	//   $1: code - HMAC of the link token
+	// Returns: The pending verification record, if any
+	GetUserVerificationByCode(ctx context.Context, code string) (*UserVerification, error)
 	// GetUsers: Retrieves paginated list of active users with search capability
 	// Purpose: List all active users for management UI
 	// Parameters:
@@ -2278,6 +2526,58 @@ type Querier interface {
 	//   total_transactions: Count of successful transactions
 	//   total_amount: Total amount processed by this method
 	GetYearlyTransactionMethodsSuccess(ctx context.Context, dollar_1 time.Time) ([]*GetYearlyTransactionMethodsSuccessRow, error)
+	// HasUserSessionOnDevice: Checks whether a user logged in from a device before
+	// Purpose: Detect new-device logins
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: device_label - Device label of the new login
+	// Returns: true when any earlier session used the same device label
+	HasUserSessionOnDevice(ctx context.Context, arg HasUserSessionOnDeviceParams) (bool, error)
+	// IncrementUserVerificationAttempts: Records a failed verification attempt
+	// Purpose: Limit guessing of short numeric codes
+	// Parameters:
+	//   $1: user_id - ID of the user
+	// Returns: The number of failed attempts so far
+	IncrementUserVerificationAttempts(ctx context.Context, userID int32) (int32, error)
+	// IsTokenRevoked: Checks whether an access token has been revoked
+	// Purpose: Consulted while validating access tokens
+	// Parameters:
+	//   $1: jti - Unique ID of the access token
+	// Returns: true when the jti is on the revocation list
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// LockLoginAttempt: Locks a throttling key until the given time
+	// Purpose: Apply a backoff delay or a lockout
+	// Parameters:
+	//   $1: attempt_key - Throttling key
+	//   $2: locked_until - End of the lock
+	LockLoginAttempt(ctx context.Context, arg LockLoginAttemptParams) error
+	// MarkTotpStepUsed: Records the time step of an accepted code
+	// Purpose: Replay protection for TOTP codes
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: last_used_step - Time step the accepted code belongs to
+	// Returns: The user ID, or no rows if the step was already used
+	// Business Logic:
+	//   - Only moves forward, so each code is accepted at most once even
+	//     under concurrent requests
+	MarkTotpStepUsed(ctx context.Context, arg MarkTotpStepUsedParams) (int32, error)
+	// RecordLoginFailure: Counts a failed login for a throttling key
+	// Purpose: Drive exponential backoff and lockout
+	// Parameters:
+	//   $1: attempt_key - Throttling key
+	//   $2: last_failure_at - Time of the failed attempt
+	//   $3: window_start - Failures before this time are forgotten
+	// Returns: The updated attempt record
+	// Business Logic:
+	//   - Increments atomically so concurrent guesses are all counted
+	//   - Restarts the count when the previous failure is outside the window
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (*LoginAttempt, error)
+	// RemovePermissionFromRole: Revokes a permission from a role
+	// Purpose: Hard delete of a role-permission mapping
+	// Parameters:
+	//   $1: Role ID
+	//   $2: Permission ID
+	RemovePermissionFromRole(ctx context.Context, arg RemovePermissionFromRoleParams) error
 	// RemoveRoleFromUser: Permanently removes a role from a user
 	// Purpose: Hard delete of a user-role mapping (bypasses trash)
 	// Parameters:
@@ -2287,6 +2587,20 @@ type Querier interface {
 	//   - Deletes the record instead of soft-deleting
 	//   - Use cautiously if audit/history is important
 	RemoveRoleFromUser(ctx context.Context, arg RemoveRoleFromUserParams) error
+	// ReserveLoginAttempt: Counts an attempt before its outcome is known
+	// Purpose: Throttle checks that must not race, such as two-factor codes
+	// Parameters:
+	//   $1: attempt_key - Throttling key
+	//   $2: failures - Failure count including this attempt
+	//   $3: last_failure_at - Time of this attempt
+	//   $4: locked_until - Lock that applies if this attempt fails, if any
+	//   $5: expected_failures - Failure count read before the attempt
+	//   $6: expected_last_failure_at - Last failure time read before the attempt
+	// Returns: 1 when the attempt was reserved, 0 when another attempt changed the record first
+	// Business Logic:
+	//   - Compare-and-swap, so concurrent attempts cannot both pass the lock check
+	//   - Inserts the record when none exists yet
+	ReserveLoginAttempt(ctx context.Context, arg ReserveLoginAttemptParams) (int64, error)
 	// RestoreAllBanners: Restore all trashed banners
 	RestoreAllBanners(ctx context.Context) error
 	// RestoreAllCategories: Recovers all trashed categories
@@ -2579,6 +2893,60 @@ type Querier interface {
 	// Business Logic:
 	//   - Clears the deleted_at field to mark as active again
 	RestoreUserRole(ctx context.Context, userRoleID int32) error
+	// RevokeOtherUserSessions: Marks all but one session of a user as logged out
+	// Purpose: "Sign out everywhere else"
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: session_id - Session to keep
+	// Returns: IDs of the revoked sessions, so their refresh tokens can be revoked
+	RevokeOtherUserSessions(ctx context.Context, arg RevokeOtherUserSessionsParams) ([]string, error)
+	// RevokeRefreshTokenFamily: Revokes every token of a token family
+	// Purpose: End a session, or contain a detected refresh token replay
+	// Parameters:
+	//   $1: family_id - Token family to revoke
+	// Business Logic:
+	//   - Soft deletes all active tokens sharing the family
+	//   - Rows are kept so replays keep being reported as revoked
+	//   - Never matches the empty family
+	//   - Marks the session of the family as revoked too
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	// RevokeRefreshTokensByUserId: Revokes every token family of a user
+	// Purpose: Logout from all devices
+	// Parameters:
+	//   $1: user_id - ID of the user whose sessions are revoked
+	// Business Logic:
+	//   - Soft deletes all active tokens of the user
+	//   - Used after password reset, ban or explicit logout-everywhere
+	//   - Marks every session of the user as revoked too
+	RevokeRefreshTokensByUserId(ctx context.Context, userID int32) error
+	// RevokeToken: Adds an access token to the revocation list
+	// Purpose: Invalidate a single access token before its natural expiry
+	// Parameters:
+	//   $1: jti - Unique ID of the access token
+	//   $2: user_id - Owner of the token
+	//   $3: expires_at - Expiry of the token, after which the entry can be purged
+	// Business Logic:
+	//   - Idempotent; revoking the same jti twice keeps the first entry
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	// RevokeUserSession: Marks a session as logged out
+	// Purpose: Sign out a single device
+	// Parameters:
+	//   $1: session_id - Refresh token family ID
+	RevokeUserSession(ctx context.Context, sessionID string) error
+	// RevokeUserTokensBefore: Revokes every access token of a user issued before a cut-off
+	// Purpose: Logout everywhere, password reset and account bans
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: revoked_before - Tokens issued before this timestamp are rejected
+	// Business Logic:
+	//   - One row per user; the cut-off only ever moves forward
+	RevokeUserTokensBefore(ctx context.Context, arg RevokeUserTokensBeforeParams) error
+	// TouchUserSession: Updates the last activity of a session
+	// Purpose: Called on every refresh token rotation
+	// Parameters:
+	//   $1: session_id - Refresh token family ID
+	//   $2: ip_address - Client IP of the refresh request
+	TouchUserSession(ctx context.Context, arg TouchUserSessionParams) error
 	// TrashBanner: Soft deletes a banner
 	// Parameters:
 	//   $1: banner_id
@@ -2919,8 +3287,11 @@ type Querier interface {
 	// Business Logic:
 	//   - Updates token and expiration fields
 	//   - Sets updated_at to current time
-	//   - Only modifies active tokens
+	//   - Only modifies the most recent active token, never other sessions
 	//   - Used during token rotation flows
+	//
+	// Deprecated: overwriting a token in place defeats reuse detection; use
+	// ExchangeRefreshToken.
 	UpdateRefreshTokenByUserId(ctx context.Context, arg UpdateRefreshTokenByUserIdParams) (*RefreshToken, error)
 	// UpdateReview: Modifies an existing review
 	// Purpose: Allow users to edit their reviews
@@ -3017,6 +3388,47 @@ type Querier interface {
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (*User, error)
 	UpdateUserIsVerified(ctx context.Context, arg UpdateUserIsVerifiedParams) (*User, error)
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (*User, error)
+	// UpsertUserTotp: Stores a new TOTP secret for a user
+	// Purpose: Start (or restart) two-factor enrolment
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: secret - Base32 encoded TOTP secret
+	// Returns: The stored TOTP record
+	// Business Logic:
+	//   - The secret stays disabled until the first code is confirmed
+	//   - Re-enrolling resets the replay counter
+	UpsertUserTotp(ctx context.Context, arg UpsertUserTotpParams) (*UserTotp, error)
+	// UpsertUserVerification: Stores a new verification code for a user
+	// Purpose: Issue or resend an email verification code
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: code - HMAC of the code or link token
+	//   $3: expires_at - When the code stops being accepted
+	//   $4: last_sent_at - Send time, used for the resend cooldown
+	// Returns: The stored verification record
+	// Business Logic:
+	//   - One pending code per user; a resend replaces the previous code
+	//   - Resets the attempts counter and records the send time for the cooldown
+	UpsertUserVerification(ctx context.Context, arg UpsertUserVerificationParams) (*UserVerification, error)
+	// UseRecoveryCode: Marks a recovery code as used
+	// Purpose: Redeem a recovery code exactly once
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: code_hash - SHA-256 hash of the submitted code
+	// Returns: The recovery code ID, or no rows if unknown or already used
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int32, error)
+	// VerifyUserWithCode: Consumes a verification code and marks the user verified
+	// Purpose: Complete email verification
+	// Parameters:
+	//   $1: user_id - ID of the user
+	//   $2: code - HMAC of the submitted code
+	//   $3: attempts - Maximum number of failed attempts allowed
+	// Returns: The verified user record, or no rows if the code was not accepted
+	// Business Logic:
+	//   - Code lookup, attempt check, deletion and the user update
+	//     run in one statement so a code can only be used once
+	//   - Clears the legacy users.verification_code column
+	VerifyUserWithCode(ctx context.Context, arg VerifyUserWithCodeParams) (*User, error)
 }
 
 var _ Querier = (*Queries)(nil)
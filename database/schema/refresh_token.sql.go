@@ -13,7 +13,7 @@ import (
 const createRefreshToken = `-- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens (user_id, token, expiration, created_at, updated_at)
 VALUES ($1, $2, $3, current_timestamp, current_timestamp)
-RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at
+RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
 `
 
 type CreateRefreshTokenParams struct {
@@ -46,10 +46,78 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return &i, err
 }
 
+const createRefreshTokenInFamily = `-- name: CreateRefreshTokenInFamily :one
+INSERT INTO refresh_tokens (user_id, token, expiration, family_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4, current_timestamp, current_timestamp)
+RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
+`
+
+type CreateRefreshTokenInFamilyParams struct {
+	UserID     int32     `json:"user_id"`
+	Token      string    `json:"token"`
+	Expiration time.Time `json:"expiration"`
+	FamilyID   string    `json:"family_id"`
+}
+
+// CreateRefreshTokenInFamily: Creates a refresh token belonging to a token family
+// Purpose: Issue the first token of a login session or the successor of a rotated token
+// Parameters:
+//
+//	$1: user_id - ID of the user this token belongs to
+//	$2: token - SHA-256 hash of the opaque refresh token
+//	$3: expiration - Expiration timestamp of the token
+//	$4: family_id - Identifier shared by every token of the same session
+//
+// Returns: The created refresh token record
+// Business Logic:
+//   - Only the hash is stored, the plaintext token is returned to the client once
+//   - Several families may exist per user (one per device/session)
+func (q *Queries) CreateRefreshTokenInFamily(ctx context.Context, arg CreateRefreshTokenInFamilyParams) (*RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, createRefreshTokenInFamily,
+		arg.UserID,
+		arg.Token,
+		arg.Expiration,
+		arg.FamilyID,
+	)
+	var i RefreshToken
+	err := row.Scan(
+		&i.RefreshTokenID,
+		&i.UserID,
+		&i.Token,
+		&i.Expiration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+	)
+	return &i, err
+}
+
+const deleteExpiredRefreshTokens = `-- name: DeleteExpiredRefreshTokens :exec
+DELETE FROM refresh_tokens
+WHERE expiration < $1
+`
+
+// DeleteExpiredRefreshTokens: Purges refresh tokens past their expiration
+// Purpose: Housekeeping of rotated and revoked rows kept for reuse detection
+// Parameters:
+//
+//	$1: expiration - Tokens expiring before this timestamp are removed
+//
+// Business Logic:
+//   - Hard deletes rows; an expired token is rejected regardless of reuse state
+func (q *Queries) DeleteExpiredRefreshTokens(ctx context.Context, expiration time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredRefreshTokens, expiration)
+	return err
+}
+
 const deleteRefreshToken = `-- name: DeleteRefreshToken :exec
 DELETE FROM refresh_tokens
 WHERE token = $1
@@ -90,8 +158,91 @@ func (q *Queries) DeleteRefreshTokenByUserId(ctx context.Context, userID int32)
 	return err
 }
 
+const exchangeRefreshToken = `-- name: ExchangeRefreshToken :one
+WITH rotated AS (
+    UPDATE refresh_tokens
+    SET rotated_at = current_timestamp, updated_at = current_timestamp
+    WHERE refresh_token_id = $1 AND rotated_at IS NULL AND deleted_at IS NULL
+    RETURNING user_id, family_id
+)
+INSERT INTO refresh_tokens (user_id, token, expiration, family_id, created_at, updated_at)
+SELECT user_id, $2::VARCHAR, $3::TIMESTAMP, family_id, current_timestamp, current_timestamp
+FROM rotated
+RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
+`
+
+type ExchangeRefreshTokenParams struct {
+	RefreshTokenID int32     `json:"refresh_token_id"`
+	Token          string    `json:"token"`
+	Expiration     time.Time `json:"expiration"`
+}
+
+// ExchangeRefreshToken: Consumes a refresh token and stores its successor
+// Purpose: Refresh token rotation
+// Parameters:
+//
+//	$1: refresh_token_id - ID of the token being exchanged
+//	$2: token - SHA-256 hash of the successor token
+//	$3: expiration - Expiration timestamp of the successor
+//
+// Returns: The successor, in the family of the exchanged token
+// Business Logic:
+//   - Marks the old token and inserts the new one in a single statement, so
+//     either both happen or neither does
+//   - Returns no rows if the token was already rotated or revoked
+//   - Keeps the old row so later replays can be detected
+func (q *Queries) ExchangeRefreshToken(ctx context.Context, arg ExchangeRefreshTokenParams) (*RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, exchangeRefreshToken, arg.RefreshTokenID, arg.Token, arg.Expiration)
+	var i RefreshToken
+	err := row.Scan(
+		&i.RefreshTokenID,
+		&i.UserID,
+		&i.Token,
+		&i.Expiration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+	)
+	return &i, err
+}
+
+const findRefreshTokenByHash = `-- name: FindRefreshTokenByHash :one
+SELECT refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
+FROM refresh_tokens
+WHERE token = $1
+`
+
+// FindRefreshTokenByHash: Retrieves a refresh token by hash regardless of state
+// Purpose: Lookup used by rotation to tell valid, rotated and revoked tokens apart
+// Parameters:
+//
+//	$1: token - SHA-256 hash of the presented refresh token
+//
+// Returns: The refresh token record including rotated and revoked tokens
+// Business Logic:
+//   - Does not filter on deleted_at or rotated_at
+//   - A hit on an already rotated token indicates token reuse
+func (q *Queries) FindRefreshTokenByHash(ctx context.Context, token string) (*RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, findRefreshTokenByHash, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.RefreshTokenID,
+		&i.UserID,
+		&i.Token,
+		&i.Expiration,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+	)
+	return &i, err
+}
+
 const findRefreshTokenByToken = `-- name: FindRefreshTokenByToken :one
-SELECT refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at
+SELECT refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
 FROM refresh_tokens
 WHERE token = $1 AND deleted_at IS NULL
 `
@@ -118,6 +269,8 @@ func (q *Queries) FindRefreshTokenByToken(ctx context.Context, token string) (*R
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return &i, err
 }
@@ -130,11 +283,13 @@ SELECT
     expiration,
     created_at,
     updated_at,
-    deleted_at
+    deleted_at,
+    family_id,
+    rotated_at
 FROM
     refresh_tokens
 WHERE
-    user_id = $1 AND deleted_at IS NULL
+    user_id = $1 AND deleted_at IS NULL AND rotated_at IS NULL
 ORDER BY
     created_at DESC
 LIMIT 1
@@ -152,6 +307,10 @@ LIMIT 1
 //   - Orders by creation date (newest first)
 //   - Used for token management and validation
 //   - Limits to 1 result to get latest token
+//   - Skips tokens already exchanged by rotation
+//
+// Deprecated: users hold one token family per session, so the newest token
+// is not necessarily the one presented; use FindRefreshTokenByHash.
 func (q *Queries) FindRefreshTokenByUserId(ctx context.Context, userID int32) (*RefreshToken, error) {
 	row := q.db.QueryRowContext(ctx, findRefreshTokenByUserId, userID)
 	var i RefreshToken
@@ -163,15 +322,76 @@ func (q *Queries) FindRefreshTokenByUserId(ctx context.Context, userID int32) (*
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return &i, err
 }
 
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+WITH revoked AS (
+    UPDATE refresh_tokens
+    SET deleted_at = current_timestamp, updated_at = current_timestamp
+    WHERE family_id = $1 AND family_id <> '' AND deleted_at IS NULL
+)
+UPDATE user_sessions
+SET revoked_at = current_timestamp
+WHERE session_id = $1 AND session_id <> '' AND revoked_at IS NULL
+`
+
+// RevokeRefreshTokenFamily: Revokes every token of a token family
+// Purpose: End a session, or contain a detected refresh token replay
+// Parameters:
+//
+//	$1: family_id - Token family to revoke
+//
+// Business Logic:
+//   - Soft deletes all active tokens sharing the family
+//   - Rows are kept so replays keep being reported as revoked
+//   - Never matches the empty family
+//   - Marks the session of the family as revoked too
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeRefreshTokensByUserId = `-- name: RevokeRefreshTokensByUserId :exec
+WITH revoked AS (
+    UPDATE refresh_tokens
+    SET deleted_at = current_timestamp, updated_at = current_timestamp
+    WHERE user_id = $1 AND deleted_at IS NULL
+)
+UPDATE user_sessions
+SET revoked_at = current_timestamp
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+// RevokeRefreshTokensByUserId: Revokes every token family of a user
+// Purpose: Logout from all devices
+// Parameters:
+//
+//	$1: user_id - ID of the user whose sessions are revoked
+//
+// Business Logic:
+//   - Soft deletes all active tokens of the user
+//   - Used after password reset, ban or explicit logout-everywhere
+//   - Marks every session of the user as revoked too
+func (q *Queries) RevokeRefreshTokensByUserId(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokensByUserId, userID)
+	return err
+}
+
 const updateRefreshTokenByUserId = `-- name: UpdateRefreshTokenByUserId :one
 UPDATE refresh_tokens
 SET token = $2, expiration = $3, updated_at = current_timestamp
-WHERE user_id = $1 AND deleted_at IS NULL
-RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at
+WHERE refresh_token_id = (
+    SELECT latest.refresh_token_id
+    FROM refresh_tokens AS latest
+    WHERE latest.user_id = $1 AND latest.deleted_at IS NULL AND latest.rotated_at IS NULL
+    ORDER BY latest.created_at DESC
+    LIMIT 1
+)
+RETURNING refresh_token_id, user_id, token, expiration, created_at, updated_at, deleted_at, family_id, rotated_at
 `
 
 type UpdateRefreshTokenByUserIdParams struct {
@@ -191,8 +411,11 @@ type UpdateRefreshTokenByUserIdParams struct {
 // Business Logic:
 //   - Updates token and expiration fields
 //   - Sets updated_at to current time
-//   - Only modifies active tokens
+//   - Only modifies the most recent active token, never other sessions
 //   - Used during token rotation flows
+//
+// Deprecated: overwriting a token in place defeats reuse detection; use
+// ExchangeRefreshToken.
 func (q *Queries) UpdateRefreshTokenByUserId(ctx context.Context, arg UpdateRefreshTokenByUserIdParams) (*RefreshToken, error) {
 	row := q.db.QueryRowContext(ctx, updateRefreshTokenByUserId, arg.UserID, arg.Token, arg.Expiration)
 	var i RefreshToken
@@ -204,6 +427,8 @@ func (q *Queries) UpdateRefreshTokenByUserId(ctx context.Context, arg UpdateRefr
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return &i, err
 }
@@ -10,6 +10,32 @@ import (
 	"time"
 )
 
+const consumeResetToken = `-- name: ConsumeResetToken :one
+DELETE FROM reset_tokens WHERE token = $1 RETURNING id, user_id, token, expiry_date
+`
+
+// ConsumeResetToken: Deletes a reset token and returns it
+// Purpose: Single-use redemption of a password reset token
+// Parameters:
+//
+//	$1: token - Hash of the reset token
+//
+// Returns: The deleted token, or no rows if it was already used
+// Business Logic:
+//   - Lookup and delete happen in one statement so concurrent requests
+//     cannot redeem the same token twice
+func (q *Queries) ConsumeResetToken(ctx context.Context, token string) (*ResetToken, error) {
+	row := q.db.QueryRowContext(ctx, consumeResetToken, token)
+	var i ResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiryDate,
+	)
+	return &i, err
+}
+
 const createResetToken = `-- name: CreateResetToken :one
 INSERT INTO
     reset_tokens (user_id, token, expiry_date)
@@ -19,7 +45,7 @@ RETURNING
 `
 
 type CreateResetTokenParams struct {
-	UserID     int64     `json:"user_id"`
+	UserID     int32     `json:"user_id"`
 	Token      string    `json:"token"`
 	ExpiryDate time.Time `json:"expiry_date"`
 }
@@ -40,7 +66,7 @@ const deleteResetToken = `-- name: DeleteResetToken :exec
 DELETE FROM reset_tokens WHERE user_id = $1
 `
 
-func (q *Queries) DeleteResetToken(ctx context.Context, userID int64) error {
+func (q *Queries) DeleteResetToken(ctx context.Context, userID int32) error {
 	_, err := q.db.ExecContext(ctx, deleteResetToken, userID)
 	return err
 }
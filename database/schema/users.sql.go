@@ -122,18 +122,19 @@ func (q *Queries) DeleteUserPermanently(ctx context.Context, userID int32) error
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT user_id, firstname, lastname, email, password, verification_code, is_verified, created_at, updated_at, deleted_at FROM users WHERE email = $1 AND deleted_at IS NULL
+SELECT user_id, firstname, lastname, email, password, verification_code, is_verified, created_at, updated_at, deleted_at FROM users WHERE lower(email) = lower($1::VARCHAR) AND deleted_at IS NULL
 `
 
 // GetUserByEmail: Retrieves active user by email
 // Purpose: Lookup user by email address (for authentication)
 // Parameters:
 //
-//	$1: email - Exact email address to search for
+//	$1: email - Email address to search for, in any case
 //
 // Returns: User record if found and active
 // Business Logic:
-//   - Case-sensitive exact match on email
+//   - Case-insensitive match on email, so addresses typed with a
+//     different case still find the account
 //   - Excludes deleted users
 //   - Used during login/authentication flows
 //   - Helps prevent duplicate accounts
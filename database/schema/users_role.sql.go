@@ -137,6 +137,57 @@ func (q *Queries) GetTrashedUserRoles(ctx context.Context, userID int32) ([]*Get
 	return items, nil
 }
 
+const getUserRoleNames = `-- name: GetUserRoleNames :many
+SELECT 
+    r.role_name
+FROM 
+    user_roles ur
+JOIN 
+    roles r ON ur.role_id = r.role_id
+WHERE 
+    ur.user_id = $1
+    AND ur.deleted_at IS NULL
+    AND r.deleted_at IS NULL
+ORDER BY 
+    r.role_name ASC
+`
+
+// GetUserRoleNames: Retrieves the names of all active roles assigned to a user
+// Purpose: Populate role claims when issuing access tokens
+// Parameters:
+//
+//	$1: User ID
+//
+// Returns:
+//
+//	List of role names
+//
+// Business Logic:
+//   - Ignores trashed user-role mappings and trashed roles
+//   - Orders alphabetically for stable token contents
+func (q *Queries) GetUserRoleNames(ctx context.Context, userID int32) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getUserRoleNames, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var role_name string
+		if err := rows.Scan(&role_name); err != nil {
+			return nil, err
+		}
+		items = append(items, role_name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeRoleFromUser = `-- name: RemoveRoleFromUser :exec
 DELETE FROM user_roles
 WHERE 
@@ -0,0 +1,76 @@
+package seeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+type permissionSeeder struct {
+	db     *db.Queries
+	ctx    context.Context
+	logger logger.LoggerInterface
+}
+
+func NewPermissionSeeder(db *db.Queries, ctx context.Context, logger logger.LoggerInterface) *permissionSeeder {
+	return &permissionSeeder{
+		db:     db,
+		ctx:    ctx,
+		logger: logger,
+	}
+}
+
+func (r *permissionSeeder) Seed() error {
+	rolePermissions := map[string][]string{
+		"Admin":    {"*"},
+		"Manager":  {"product:*", "category:*", "banner:*", "slider:*", "order:read", "merchant:read", "review:read"},
+		"Cashier":  {"product:read", "order:read", "order:write", "transaction:read", "transaction:write"},
+		"Supplier": {"product:read", "product:write:own", "merchant:read:own", "merchant:write:own", "order:read:own"},
+	}
+
+	permissionIDs := make(map[string]int32)
+	totalPermissions := 0
+
+	for roleName, names := range rolePermissions {
+		role, err := r.db.GetRoleByName(r.ctx, roleName)
+		if err != nil {
+			r.logger.Error("failed to fetch role", zap.String("roleName", roleName), zap.Error(err))
+			return fmt.Errorf("failed to fetch role %s: %w", roleName, err)
+		}
+
+		for _, name := range names {
+			permissionID, ok := permissionIDs[name]
+			if !ok {
+				permission, err := r.db.CreatePermission(r.ctx, db.CreatePermissionParams{
+					Name:        name,
+					Description: sql.NullString{String: fmt.Sprintf("Seeded permission %s", name), Valid: true},
+				})
+				if err != nil {
+					r.logger.Error("failed to seed permission", zap.String("permission", name), zap.Error(err))
+					return fmt.Errorf("failed to seed permission %s: %w", name, err)
+				}
+
+				permissionID = permission.PermissionID
+				permissionIDs[name] = permissionID
+				totalPermissions++
+			}
+
+			_, err := r.db.AssignPermissionToRole(r.ctx, db.AssignPermissionToRoleParams{
+				RoleID:       role.RoleID,
+				PermissionID: permissionID,
+			})
+			if err != nil {
+				r.logger.Error("failed to assign permission to role", zap.String("role", roleName), zap.String("permission", name), zap.Error(err))
+				return fmt.Errorf("failed to assign permission %s to role %s: %w", name, roleName, err)
+			}
+		}
+	}
+
+	r.logger.Debug("permissions seeded successfully", zap.Int("totalPermissions", totalPermissions))
+	return nil
+}
@@ -23,6 +23,7 @@ type Seeder struct {
 	User             *userSeeder
 	Role             *roleSeeder
 	UserRole         *userRoleSeeder
+	Permission       *permissionSeeder
 	Merchant         *merchantSeeder
 	MerchantDetail   *merchantDetailSeeder
 	MerchantAward    *merchantAwardSeeder
@@ -39,10 +40,13 @@ type Seeder struct {
 }
 
 func NewSeeder(deps Deps) *Seeder {
+	deps.Logger = logger.Named(deps.Logger, "seeder")
+
 	return &Seeder{
 		User:             NewUserSeeder(deps.Db, deps.Hash, deps.Ctx, deps.Logger),
 		Role:             NewRoleSeeder(deps.Db, deps.Ctx, deps.Logger),
 		UserRole:         NewUserRoleSeeder(deps.Db, deps.Ctx, deps.Logger),
+		Permission:       NewPermissionSeeder(deps.Db, deps.Ctx, deps.Logger),
 		Merchant:         NewMerchantSeeder(deps.Db, deps.Ctx, deps.Logger),
 		MerchantDetail:   NewMerchantDetailSeeder(deps.Db, deps.Ctx, deps.Logger),
 		MerchantAward:    NewMerchantAwardSeeder(deps.Db, deps.Ctx, deps.Logger),
@@ -92,6 +96,10 @@ func (s *Seeder) RunSession1() error {
 		return err
 	}
 
+	if err := s.seedWithDelay("permissions", s.Permission.Seed); err != nil {
+		return err
+	}
+
 	return nil
 }
 
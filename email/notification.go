@@ -0,0 +1,31 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+// NewDeviceLoginMessage warns a user about a login from a device they have
+// not used before. link points to the page where sessions can be revoked.
+// name, device and ip are escaped, as device comes from the User-Agent.
+func NewDeviceLoginMessage(to string, name string, device string, ip string, at time.Time, link string) Message {
+	subject := "New login to your account"
+
+	body := GenerateEmailHTML(map[string]string{
+		"Subject": subject,
+		"Title":   subject,
+		"Message": fmt.Sprintf(
+			"Hi %s, your account was just signed in from %s (IP %s) at %s. If this was you, you can ignore this email. Otherwise, sign out that session and change your password.",
+			html.EscapeString(name), html.EscapeString(device), html.EscapeString(ip), at.UTC().Format("2006-01-02 15:04 MST"),
+		),
+		"Link":   link,
+		"Button": "Review Sessions",
+	})
+
+	return Message{
+		Email:   to,
+		Subject: subject,
+		Body:    body,
+	}
+}
@@ -0,0 +1,62 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Message is the payload consumed by the email service.
+type Message struct {
+	Email   string `json:"email"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+//go:generate mockgen -source=sender.go -destination=mocks/sender.go
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Producer publishes a message to a topic; *kafka.Kafka satisfies it.
+type Producer interface {
+	SendMessage(topic string, key string, value []byte) error
+}
+
+// contextProducer is implemented by producers that propagate the trace in
+// ctx, such as *kafka.Kafka.
+type contextProducer interface {
+	SendMessageContext(ctx context.Context, topic string, key string, value []byte) error
+}
+
+type kafkaSender struct {
+	producer Producer
+	topic    string
+}
+
+// NewKafkaSender hands messages to the email service through topic, keyed
+// by recipient so mails to the same address stay in order.
+func NewKafkaSender(producer Producer, topic string) Sender {
+	return &kafkaSender{
+		producer: producer,
+		topic:    topic,
+	}
+}
+
+func (s *kafkaSender) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal email message: %w", err)
+	}
+
+	if p, ok := s.producer.(contextProducer); ok {
+		err = p.SendMessageContext(ctx, s.topic, msg.Email, payload)
+	} else {
+		err = s.producer.SendMessage(s.topic, msg.Email, payload)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish email message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+var ErrInvalidHash = errors.New("invalid password hash format")
+
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the OWASP recommendation for Argon2id.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// DefaultArgon2Limits bounds the Memory, Iterations and Parallelism read
+// from stored hashes; SaltLength and KeyLength are not checked.
+var DefaultArgon2Limits = Argon2Params{
+	Memory:      256 * 1024,
+	Iterations:  10,
+	Parallelism: 16,
+}
+
+type argon2Hash struct {
+	params Argon2Params
+	keyID  string
+	salt   []byte
+	key    []byte
+}
+
+func newArgon2Hash(password []byte, params Argon2Params, keyID string) (*argon2Hash, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return &argon2Hash{
+		params: params,
+		keyID:  keyID,
+		salt:   salt,
+		key:    argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength),
+	}, nil
+}
+
+// encode renders the hash as a PHC string, e.g.
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>. The optional keyid
+// parameter names the pepper the password was mixed with.
+func (h *argon2Hash) encode() string {
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", h.params.Memory, h.params.Iterations, h.params.Parallelism)
+	if h.keyID != "" {
+		params += ",keyid=" + h.keyID
+	}
+
+	return fmt.Sprintf("%sv=%d$%s$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params,
+		base64.RawStdEncoding.EncodeToString(h.salt),
+		base64.RawStdEncoding.EncodeToString(h.key),
+	)
+}
+
+func decodeArgon2Hash(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("%w: unsupported argon2 version %d", ErrInvalidHash, version)
+	}
+
+	h := &argon2Hash{}
+	for _, kv := range strings.Split(parts[3], ",") {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, ErrInvalidHash
+		}
+
+		switch key {
+		case "m", "t", "p":
+			bitSize := 32
+			if key == "p" {
+				bitSize = 8
+			}
+			n, err := strconv.ParseUint(value, 10, bitSize)
+			if err != nil {
+				return nil, ErrInvalidHash
+			}
+			switch key {
+			case "m":
+				h.params.Memory = uint32(n)
+			case "t":
+				h.params.Iterations = uint32(n)
+			case "p":
+				h.params.Parallelism = uint8(n)
+			}
+		case "keyid":
+			h.keyID = value
+		}
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	h.params.SaltLength = uint32(len(h.salt))
+	h.params.KeyLength = uint32(len(h.key))
+
+	if h.params.Memory == 0 || h.params.Iterations == 0 || h.params.Parallelism == 0 || h.params.KeyLength == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	return h, nil
+}
+
+func (h *argon2Hash) verify(password []byte) bool {
+	other := argon2.IDKey(password, h.salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return subtle.ConstantTimeCompare(h.key, other) == 1
+}
+
+func (h *argon2Hash) weakerThan(p Argon2Params) bool {
+	return h.params.Memory < p.Memory ||
+		h.params.Iterations < p.Iterations ||
+		h.params.Parallelism < p.Parallelism ||
+		h.params.SaltLength < p.SaltLength ||
+		h.params.KeyLength < p.KeyLength
+}
+
+func (h *argon2Hash) exceeds(limits Argon2Params) bool {
+	return h.params.Memory > limits.Memory ||
+		h.params.Iterations > limits.Iterations ||
+		h.params.Parallelism > limits.Parallelism
+}
+
+func isArgon2idHash(hashPassword string) bool {
+	return strings.HasPrefix(hashPassword, argon2idPrefix)
+}
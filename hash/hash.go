@@ -1,13 +1,19 @@
 package hash
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUnsupportedHash    = errors.New("unsupported password hash algorithm")
+	ErrUnknownPepper      = errors.New("unknown password pepper")
 )
 
 //go:generate mockgen -source=hash.go -destination=mocks/hash.go
@@ -16,8 +22,17 @@ type HashPassword interface {
 	ComparePassword(hashPassword string, password string) error
 }
 
+// RehashingPassword also tells when a stored hash is outdated and should be
+// replaced after the next successful login.
+type RehashingPassword interface {
+	HashPassword
+	NeedsRehash(hashPassword string) bool
+}
+
 type Hashing struct{}
 
+// NewHashingPassword hashes with bcrypt at the default cost. Use
+// NewArgon2HashingPassword to move to Argon2id.
 func NewHashingPassword() HashPassword {
 	return &Hashing{}
 }
@@ -34,3 +49,142 @@ func (h Hashing) HashPassword(password string) (string, error) {
 func (h Hashing) ComparePassword(hashPassword string, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashPassword), []byte(password))
 }
+
+func (h Hashing) NeedsRehash(hashPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashPassword))
+	return err != nil || cost < bcrypt.DefaultCost
+}
+
+type Option func(*PasswordHasher)
+
+func WithArgon2Params(params Argon2Params) Option {
+	return func(h *PasswordHasher) {
+		h.params = params
+	}
+}
+
+// WithArgon2Limits caps the parameters ComparePassword accepts from a
+// stored hash, so a tampered hash cannot make a login allocate gigabytes of
+// memory or run for minutes. Limits below the parameters of new hashes are
+// raised to them.
+func WithArgon2Limits(limits Argon2Params) Option {
+	return func(h *PasswordHasher) {
+		h.limits = limits
+	}
+}
+
+// WithPepper mixes secret into every new hash via HMAC-SHA256 before
+// hashing. id is written to the PHC string so the pepper can be rotated:
+// register the old one with WithPreviousPepper and NeedsRehash reports the
+// affected hashes.
+func WithPepper(id string, secret []byte) Option {
+	return func(h *PasswordHasher) {
+		h.pepperID = id
+		h.peppers[id] = secret
+	}
+}
+
+func WithPreviousPepper(id string, secret []byte) Option {
+	return func(h *PasswordHasher) {
+		h.peppers[id] = secret
+	}
+}
+
+// PasswordHasher hashes new passwords with Argon2id and verifies both
+// Argon2id and legacy bcrypt hashes, picking the algorithm from the stored
+// hash. After a successful login, callers should check NeedsRehash and store
+// a fresh hash when it returns true.
+type PasswordHasher struct {
+	params   Argon2Params
+	limits   Argon2Params
+	pepperID string
+	peppers  map[string][]byte
+	bcrypt   Hashing
+}
+
+// NewArgon2HashingPassword hashes with DefaultArgon2Params unless changed
+// with WithArgon2Params, and accepts stored hashes up to
+// DefaultArgon2Limits.
+func NewArgon2HashingPassword(opts ...Option) RehashingPassword {
+	h := &PasswordHasher{
+		params:  DefaultArgon2Params,
+		limits:  DefaultArgon2Limits,
+		peppers: make(map[string][]byte),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.limits.Memory = max(h.limits.Memory, h.params.Memory)
+	h.limits.Iterations = max(h.limits.Iterations, h.params.Iterations)
+	h.limits.Parallelism = max(h.limits.Parallelism, h.params.Parallelism)
+
+	return h
+}
+
+func (h *PasswordHasher) HashPassword(password string) (string, error) {
+	encoded, err := newArgon2Hash(h.pepper(h.pepperID, password), h.params, h.pepperID)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return encoded.encode(), nil
+}
+
+func (h *PasswordHasher) ComparePassword(hashPassword string, password string) error {
+	switch {
+	case isArgon2idHash(hashPassword):
+		decoded, err := decodeArgon2Hash(hashPassword)
+		if err != nil {
+			return err
+		}
+		if decoded.exceeds(h.limits) {
+			return fmt.Errorf("%w: argon2 parameters exceed the configured limits", ErrInvalidHash)
+		}
+		if decoded.keyID != "" {
+			if _, ok := h.peppers[decoded.keyID]; !ok {
+				return fmt.Errorf("%w: %q", ErrUnknownPepper, decoded.keyID)
+			}
+		}
+		if !decoded.verify(h.pepper(decoded.keyID, password)) {
+			return ErrInvalidCredentials
+		}
+		return nil
+	case isBcryptHash(hashPassword):
+		if err := h.bcrypt.ComparePassword(hashPassword, password); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+		}
+		return nil
+	default:
+		return ErrUnsupportedHash
+	}
+}
+
+func (h *PasswordHasher) NeedsRehash(hashPassword string) bool {
+	if !isArgon2idHash(hashPassword) {
+		return true
+	}
+
+	decoded, err := decodeArgon2Hash(hashPassword)
+	if err != nil {
+		return true
+	}
+
+	return decoded.keyID != h.pepperID || decoded.weakerThan(h.params)
+}
+
+func (h *PasswordHasher) pepper(id string, password string) []byte {
+	secret, ok := h.peppers[id]
+	if id == "" || !ok {
+		return []byte(password)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+func isBcryptHash(hashPassword string) bool {
+	return strings.HasPrefix(hashPassword, "$2a$") ||
+		strings.HasPrefix(hashPassword, "$2b$") ||
+		strings.HasPrefix(hashPassword, "$2y$")
+}
@@ -2,9 +2,14 @@ package kafka
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/IBM/sarama"
 	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -14,7 +19,9 @@ type Kafka struct {
 	brokers  []string
 }
 
-func NewKafka(logger logger.LoggerInterface, brokers []string) *Kafka {
+func NewKafka(l logger.LoggerInterface, brokers []string) *Kafka {
+	log := logger.Named(l, "kafka")
+
 	config := sarama.NewConfig()
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 5
@@ -22,31 +29,53 @@ func NewKafka(logger logger.LoggerInterface, brokers []string) *Kafka {
 
 	producer, err := sarama.NewSyncProducer(brokers, config)
 	if err != nil {
-		logger.Fatal("Failed to create Kafka producer", zap.Error(err))
+		log.Fatal("Failed to create Kafka producer", zap.Error(err))
 	}
 
-	logger.Info("Kafka producer connected successfully")
+	log.Info("Kafka producer connected successfully")
 
 	return &Kafka{
 		producer: producer,
 		brokers:  brokers,
-		logger:   logger,
+		logger:   log,
 	}
 }
 
 func (k *Kafka) SendMessage(topic string, key string, value []byte) error {
+	return k.SendMessageContext(context.Background(), topic, key, value)
+}
+
+// SendMessageContext publishes in a "<topic> publish" span and injects the
+// W3C traceparent and baggage from ctx into the record headers, so consumers
+// using NewTracingHandler or ContextFromMessage continue the trace.
+func (k *Kafka) SendMessageContext(ctx context.Context, topic string, key string, value []byte) error {
 	msg := &sarama.ProducerMessage{
 		Topic: topic,
 		Key:   sarama.StringEncoder(key),
 		Value: sarama.ByteEncoder(value),
 	}
 
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(publishAttributes(msg)...),
+	)
+	defer span.End()
+
+	injectMessage(ctx, msg)
+
 	partition, offset, err := k.producer.SendMessage(msg)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	k.logger.Info("Message is stored in topic", zap.String("topic", topic), zap.Int32("partition", partition), zap.Int64("offset", offset))
+	span.SetAttributes(
+		semconv.MessagingDestinationPartitionID(strconv.Itoa(int(partition))),
+		semconv.MessagingKafkaMessageOffset(int(offset)),
+	)
+
+	logger.FromContext(ctx, k.logger).Info("Message is stored in topic", zap.String("topic", topic), zap.Int32("partition", partition), zap.Int64("offset", offset))
 
 	return nil
 }
@@ -0,0 +1,199 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const instrumentationName = "github.com/MamangRust/monolith-ecommerce-pkg/kafka"
+
+var (
+	_ propagation.TextMapCarrier = producerCarrier{}
+	_ propagation.TextMapCarrier = consumerCarrier{}
+)
+
+// producerCarrier exposes the headers of an outgoing message to the
+// propagator.
+type producerCarrier struct {
+	msg *sarama.ProducerMessage
+}
+
+func (c producerCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c producerCarrier) Set(key, value string) {
+	for i, h := range c.msg.Headers {
+		if string(h.Key) == key {
+			c.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c producerCarrier) Keys() []string {
+	keys := make([]string, len(c.msg.Headers))
+	for i, h := range c.msg.Headers {
+		keys[i] = string(h.Key)
+	}
+	return keys
+}
+
+type consumerCarrier struct {
+	msg *sarama.ConsumerMessage
+}
+
+func (c consumerCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c consumerCarrier) Set(key, value string) {
+	for _, h := range c.msg.Headers {
+		if h != nil && string(h.Key) == key {
+			h.Value = []byte(value)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, &sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c consumerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Headers))
+	for _, h := range c.msg.Headers {
+		if h != nil {
+			keys = append(keys, string(h.Key))
+		}
+	}
+	return keys
+}
+
+// ContextFromMessage returns ctx carrying the trace context and baggage
+// the producer injected into msg's headers.
+func ContextFromMessage(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, consumerCarrier{msg: msg})
+}
+
+func injectMessage(ctx context.Context, msg *sarama.ProducerMessage) {
+	otel.GetTextMapPropagator().Inject(ctx, producerCarrier{msg: msg})
+}
+
+// MessageHandler processes one message; ctx carries the consumer span.
+type MessageHandler func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+type tracingHandler struct {
+	handle  MessageHandler
+	groupID string
+	logger  logger.LoggerInterface
+	tracer  trace.Tracer
+}
+
+// NewTracingHandler adapts handle to a sarama.ConsumerGroupHandler for
+// StartConsumers. Each message is processed in a "<topic> process" span
+// that continues the producer's trace and links to its span. Failures are
+// recorded on the span and logged; the message is marked either way, so
+// retries belong in handle.
+func NewTracingHandler(groupID string, logger logger.LoggerInterface, handle MessageHandler) sarama.ConsumerGroupHandler {
+	return &tracingHandler{
+		handle:  handle,
+		groupID: groupID,
+		logger:  logger,
+		tracer:  otel.Tracer(instrumentationName),
+	}
+}
+
+func (h *tracingHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *tracingHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *tracingHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			h.process(session.Context(), msg)
+			session.MarkMessage(msg, "")
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (h *tracingHandler) process(ctx context.Context, msg *sarama.ConsumerMessage) {
+	parent := ContextFromMessage(ctx, msg)
+
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingOperationName("process"),
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(msg.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+			semconv.MessagingKafkaConsumerGroup(h.groupID),
+			semconv.MessagingMessageBodySize(len(msg.Value)),
+		),
+	}
+	if producer := trace.SpanContextFromContext(parent); producer.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: producer}))
+	}
+
+	ctx, span := h.tracer.Start(parent, msg.Topic+" process", opts...)
+	defer span.End()
+
+	if err := h.handle(ctx, msg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		if h.logger != nil {
+			logger.FromContext(ctx, h.logger).Error("Failed to process message",
+				zap.String("topic", msg.Topic),
+				zap.Int32("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// publishAttributes leaves out the message key, as producers such as
+// email.Sender key by email address.
+func publishAttributes(msg *sarama.ProducerMessage) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		semconv.MessagingOperationTypePublish,
+		semconv.MessagingOperationName("publish"),
+		semconv.MessagingDestinationName(msg.Topic),
+	}
+	if msg.Value != nil {
+		attrs = append(attrs, semconv.MessagingMessageBodySize(msg.Value.Length()))
+	}
+	return attrs
+}
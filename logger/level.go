@@ -0,0 +1,217 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels holds the minimum level of the root logger and of named child
+// loggers. Names are matched on their dotted prefix, so a level set for
+// "kafka" also applies to "kafka.consumer" unless that has its own.
+type Levels struct {
+	base  zap.AtomicLevel
+	mu    sync.RWMutex
+	named map[string]zapcore.Level
+}
+
+func NewLevels(base zapcore.Level) *Levels {
+	return &Levels{
+		base:  zap.NewAtomicLevelAt(base),
+		named: make(map[string]zapcore.Level),
+	}
+}
+
+// levelsFromViper reads LOG_LEVEL (default info) and LOG_LEVELS, a comma
+// separated list of name=level pairs such as "kafka=warn,seeder=debug".
+func levelsFromViper() (*Levels, error) {
+	base := zapcore.InfoLevel
+	if v := viper.GetString("LOG_LEVEL"); v != "" {
+		lvl, err := zapcore.ParseLevel(v)
+		if err != nil {
+			return NewLevels(base), fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+		base = lvl
+	}
+
+	levels := NewLevels(base)
+
+	for _, pair := range strings.Split(viper.GetString("LOG_LEVELS"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return levels, fmt.Errorf("invalid LOG_LEVELS entry %q", pair)
+		}
+
+		lvl, err := zapcore.ParseLevel(strings.TrimSpace(value))
+		if err != nil {
+			return levels, fmt.Errorf("invalid LOG_LEVELS entry %q: %w", pair, err)
+		}
+		levels.SetNamedLevel(strings.TrimSpace(name), lvl)
+	}
+
+	return levels, nil
+}
+
+func (l *Levels) Level() zapcore.Level {
+	return l.base.Level()
+}
+
+func (l *Levels) SetLevel(lvl zapcore.Level) {
+	l.base.SetLevel(lvl)
+}
+
+// NamedLevel returns the level in effect for name.
+func (l *Levels) NamedLevel(name string) zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for name != "" {
+		if lvl, ok := l.named[name]; ok {
+			return lvl
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+
+	return l.base.Level()
+}
+
+func (l *Levels) SetNamedLevel(name string, lvl zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.named[name] = lvl
+}
+
+// ResetNamedLevel makes name follow its parent or the root level again.
+func (l *Levels) ResetNamedLevel(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.named, name)
+}
+
+func (l *Levels) Enabled(name string, lvl zapcore.Level) bool {
+	return l.NamedLevel(name).Enabled(lvl)
+}
+
+type levelsPayload struct {
+	Level   string            `json:"level"`
+	Loggers map[string]string `json:"loggers,omitempty"`
+}
+
+type levelRequest struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// ServeHTTP reports the current levels on GET. PUT or POST with
+// {"level":"debug"} changes the root level and {"name":"kafka","level":"warn"}
+// a named one; an empty level with a name removes the override. Mount it
+// behind authentication, e.g. e.Any("/admin/log-level", echo.WrapHandler(levels)).
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req levelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeLevelError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+
+		if req.Name != "" && req.Level == "" {
+			l.ResetNamedLevel(req.Name)
+			break
+		}
+
+		lvl, err := zapcore.ParseLevel(req.Level)
+		if err != nil {
+			writeLevelError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Name == "" {
+			l.SetLevel(lvl)
+		} else {
+			l.SetNamedLevel(req.Name, lvl)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeLevelError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	writeLevelJSON(w, http.StatusOK, l.snapshot())
+}
+
+func (l *Levels) snapshot() levelsPayload {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	payload := levelsPayload{Level: l.base.Level().String()}
+	if len(l.named) > 0 {
+		payload.Loggers = make(map[string]string, len(l.named))
+		for name, lvl := range l.named {
+			payload.Loggers[name] = lvl.String()
+		}
+	}
+
+	return payload
+}
+
+func writeLevelError(w http.ResponseWriter, status int, err error) {
+	writeLevelJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// levelCore filters entries by the level Levels holds for name, so the
+// underlying cores can stay at DebugLevel and levels change at runtime.
+type levelCore struct {
+	zapcore.Core
+	levels *Levels
+	name   string
+}
+
+func newLevelCore(core zapcore.Core, levels *Levels, name string) zapcore.Core {
+	if lc, ok := core.(*levelCore); ok {
+		core = lc.Core
+	}
+	return &levelCore{Core: core, levels: levels, name: name}
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.Enabled(c.name, lvl)
+}
+
+func (c *levelCore) Level() zapcore.Level {
+	return c.levels.NamedLevel(c.name)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels, name: c.name}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -1,12 +1,15 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"sync"
 
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -16,84 +19,151 @@ type LoggerInterface interface {
 	Info(message string, fields ...zap.Field)
 	Fatal(message string, fields ...zap.Field)
 	Debug(message string, fields ...zap.Field)
+	Warn(message string, fields ...zap.Field)
 	Error(message string, fields ...zap.Field)
+	With(fields ...zap.Field) LoggerInterface
+	Sync() error
 }
 
 type Logger struct {
 	Log *zap.Logger
+
+	levels *Levels
+	name   string
+	file   *fileHandle
+}
+
+// fileHandle is the reference to a shared log file that one NewLogger call
+// holds, released at most once however many children call Close.
+type fileHandle struct {
+	once sync.Once
+	file *rotatingFile
+	err  error
+}
+
+func (h *fileHandle) close() error {
+	h.once.Do(func() {
+		h.err = h.file.Close()
+	})
+	return h.err
 }
 
-var once sync.Once
-var instance LoggerInterface
+type Option func(*options)
+
+type options struct {
+	cores []zapcore.Core
+}
+
+// WithCore adds an extra destination, such as an OTLPCore, next to stdout
+// and the log file. It goes through the same level and redaction rules.
+func WithCore(core zapcore.Core) Option {
+	return func(o *options) {
+		o.cores = append(o.cores, core)
+	}
+}
+
+// NewLogger builds a logger writing JSON to stdout and to <service>.log in
+// the log directory. Every call returns an independent instance, but
+// loggers with the same log path share the open file and its rotation,
+// configured by the first of them. When the file cannot be opened the
+// logger still works on stdout and the setup error is returned alongside
+// it.
+func NewLogger(service string, opts ...Option) (LoggerInterface, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-func NewLogger(service string) (LoggerInterface, error) {
 	var setupErr error
 
-	once.Do(func() {
-		env := os.Getenv("APP_ENV")
-		if env == "" {
-			env = "development"
-		}
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "development"
+	}
 
-		logDir := "./logs"
+	logDir := "./logs"
 
-		if env == "docker" || env == "production" || env == "kubernetes" {
-			logDir = "/var/log/app"
-		}
+	if env == "docker" || env == "production" || env == "kubernetes" {
+		logDir = "/var/log/app"
+	}
+
+	if dir := viper.GetString("LOG_DIR"); dir != "" {
+		logDir = dir
+	}
+
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		setupErr = fmt.Errorf("failed to create log directory '%s': %w", logDir, err)
+		log.Println("[WARN] Fallback to stdout only:", setupErr)
+	}
 
-		if err := os.MkdirAll(logDir, 0755); err != nil {
-			setupErr = fmt.Errorf("failed to create log directory '%s': %w", logDir, err)
+	logPath := filepath.Join(logDir, fmt.Sprintf("%s.log", service))
+
+	var logFile *rotatingFile
+	if setupErr == nil {
+		logFile = openRotatingFile(logPath, rotationConfigFromViper())
+
+		// lumberjack opens lazily; open now so a bad path falls back
+		// to stdout here instead of failing on every write.
+		if _, err := logFile.Write(nil); err != nil {
+			setupErr = fmt.Errorf("failed to open log file '%s': %w", logPath, err)
 			log.Println("[WARN] Fallback to stdout only:", setupErr)
+			logFile.Close()
+			logFile = nil
 		}
+	}
 
-		logPath := filepath.Join(logDir, fmt.Sprintf("%s.log", service))
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
 
-		var logFile *os.File
-		if setupErr == nil {
-			var err error
-			logFile, err = os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				setupErr = fmt.Errorf("failed to open log file '%s': %w", logPath, err)
-				log.Println("[WARN] Fallback to stdout only:", setupErr)
-			}
-		}
+	levels, err := levelsFromViper()
+	if err != nil {
+		log.Println("[WARN] Ignoring log level config:", err)
+	}
 
-		encoderConfig := zapcore.EncoderConfig{
-			TimeKey:        "ts",
-			LevelKey:       "level",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			FunctionKey:    zapcore.OmitKey,
-			MessageKey:     "msg",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,
-			EncodeTime:     zapcore.ISO8601TimeEncoder,
-			EncodeDuration: zapcore.StringDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-		}
+	cores := []zapcore.Core{
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(encoderConfig),
+			zapcore.AddSync(os.Stdout),
+			zapcore.DebugLevel,
+		),
+	}
 
-		cores := []zapcore.Core{
-			zapcore.NewCore(
-				zapcore.NewJSONEncoder(encoderConfig),
-				zapcore.AddSync(os.Stdout),
-				zapcore.DebugLevel,
-			),
-		}
+	if logFile != nil {
+		cores = append(cores, zapcore.NewCore(
+			zapcore.NewJSONEncoder(encoderConfig),
+			zapcore.AddSync(logFile),
+			zapcore.DebugLevel,
+		))
+	}
 
-		if logFile != nil {
-			cores = append(cores, zapcore.NewCore(
-				zapcore.NewJSONEncoder(encoderConfig),
-				zapcore.AddSync(logFile),
-				zapcore.DebugLevel,
-			))
-		}
+	cores = append(cores, o.cores...)
 
-		logger := zap.New(zapcore.NewTee(cores...), zap.AddCaller(), zap.AddCallerSkip(1))
-		instance = &Logger{Log: logger}
-	})
+	core := zapcore.NewTee(cores...)
+	if cfg, ok := redactionConfigFromViper(); ok {
+		core = NewRedactingCore(core, cfg)
+	}
+	core = newLevelCore(core, levels, "")
+
+	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
 
-	return instance, setupErr
+	l := &Logger{Log: logger, levels: levels}
+	if logFile != nil {
+		l.file = &fileHandle{file: logFile}
+	}
+
+	return l, setupErr
 }
 
 func (l *Logger) Info(message string, fields ...zap.Field) {
@@ -108,6 +178,80 @@ func (l *Logger) Debug(message string, fields ...zap.Field) {
 	l.Log.Debug(message, fields...)
 }
 
+func (l *Logger) Warn(message string, fields ...zap.Field) {
+	l.Log.Warn(message, fields...)
+}
+
 func (l *Logger) Error(message string, fields ...zap.Field) {
 	l.Log.Error(message, fields...)
 }
+
+func (l *Logger) With(fields ...zap.Field) LoggerInterface {
+	return &Logger{Log: l.Log.With(fields...), levels: l.levels, name: l.name, file: l.file}
+}
+
+func (l *Logger) Sync() error {
+	return l.Log.Sync()
+}
+
+// Close flushes the logger and releases its log file and the timer
+// rotating it once no other logger uses them. Children made with With or
+// Named share the reference of their parent, so close only the logger
+// NewLogger returned, once nothing logs through it anymore; further calls
+// do nothing.
+func (l *Logger) Close() error {
+	l.Log.Sync()
+
+	if l.file == nil {
+		return nil
+	}
+	return l.file.close()
+}
+
+// Named returns a child logger for a component such as "kafka". Its level
+// can be set separately through Levels; nested names are dot separated.
+func (l *Logger) Named(name string) *Logger {
+	if l.levels == nil {
+		return &Logger{Log: l.Log.Named(name)}
+	}
+
+	full := name
+	if l.name != "" {
+		full = l.name + "." + name
+	}
+
+	zl := l.Log.Named(name).WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newLevelCore(core, l.levels, full)
+	}))
+
+	return &Logger{Log: zl, levels: l.levels, name: full, file: l.file}
+}
+
+// Levels returns the level registry shared by l and its children, or nil
+// when l was not built by NewLogger.
+func (l *Logger) Levels() *Levels {
+	return l.levels
+}
+
+// WithContext is shorthand for FromContext(ctx, l).
+func (l *Logger) WithContext(ctx context.Context) LoggerInterface {
+	return FromContext(ctx, l)
+}
+
+// Close is Logger.Close for a LoggerInterface, such as the one NewLogger
+// returns; other implementations are closed when they are an io.Closer.
+func Close(l LoggerInterface) error {
+	if c, ok := l.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Named is Logger.Named for a LoggerInterface; other implementations are
+// returned unchanged.
+func Named(l LoggerInterface, name string) LoggerInterface {
+	if n, ok := l.(interface{ Named(string) *Logger }); ok {
+		return n.Named(name)
+	}
+	return l
+}
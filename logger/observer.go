@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Observer is a LoggerInterface that keeps entries in memory so tests can
+// assert on messages and fields. Children created with With or Named record
+// into the same Observer. Fatal panics instead of exiting.
+type Observer struct {
+	*Logger
+	logs *observer.ObservedLogs
+}
+
+func NewObserver(level zapcore.Level) *Observer {
+	core, logs := observer.New(zapcore.DebugLevel)
+	levels := NewLevels(level)
+
+	zl := zap.New(newLevelCore(core, levels, ""), zap.WithFatalHook(zapcore.WriteThenPanic))
+
+	return &Observer{
+		Logger: &Logger{Log: zl, levels: levels},
+		logs:   logs,
+	}
+}
+
+// Logs gives access to the recorded entries and observer's filters, e.g.
+// o.Logs().FilterMessage("order created").FilterField(zap.Int("order_id", 1)).
+func (o *Observer) Logs() *observer.ObservedLogs {
+	return o.logs
+}
+
+// Entries returns the recorded entries in the order they were logged.
+func (o *Observer) Entries() []observer.LoggedEntry {
+	return o.logs.All()
+}
+
+func (o *Observer) Messages() []string {
+	entries := o.logs.All()
+
+	messages := make([]string, len(entries))
+	for i, e := range entries {
+		messages[i] = e.Message
+	}
+	return messages
+}
+
+// Reset drops everything recorded so far.
+func (o *Observer) Reset() {
+	o.logs.TakeAll()
+}
@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/contrib/bridges/otelzap"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultOTLPQueueSize = 4096
+
+type OTLPConfig struct {
+	// Name is the instrumentation scope, usually the service name.
+	Name string
+	// Provider defaults to the global LoggerProvider, as installed by
+	// otel_pkg.InitLoggerProvider.
+	Provider log.LoggerProvider
+	// QueueSize bounds the entries waiting to be handed to the provider.
+	// Entries logged while it is full are dropped and counted.
+	QueueSize int
+}
+
+// OTLPCore forwards entries to an OpenTelemetry LoggerProvider from a
+// background goroutine, so a slow or unreachable collector never blocks the
+// caller. Pass it to NewLogger with WithCore and call Close before shutting
+// the provider down.
+type OTLPCore struct {
+	inner zapcore.Core
+	queue *otlpQueue
+}
+
+type otlpRecord struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+type otlpQueue struct {
+	records chan otlpRecord
+	dropped atomic.Uint64
+	closed  atomic.Bool
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func NewOTLPCore(cfg OTLPConfig) *OTLPCore {
+	if cfg.Provider == nil {
+		cfg.Provider = global.GetLoggerProvider()
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultOTLPQueueSize
+	}
+
+	q := &otlpQueue{
+		records: make(chan otlpRecord, cfg.QueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go q.run()
+
+	return &OTLPCore{
+		inner: otelzap.NewCore(cfg.Name, otelzap.WithLoggerProvider(cfg.Provider)),
+		queue: q,
+	}
+}
+
+// Dropped reports how many entries were discarded because the queue was
+// full or the core was closed.
+func (c *OTLPCore) Dropped() uint64 {
+	return c.queue.dropped.Load()
+}
+
+func (c *OTLPCore) Enabled(lvl zapcore.Level) bool {
+	return c.inner.Enabled(lvl)
+}
+
+func (c *OTLPCore) With(fields []zapcore.Field) zapcore.Core {
+	return &OTLPCore{inner: c.inner.With(snapshotFields(fields)), queue: c.queue}
+}
+
+func (c *OTLPCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *OTLPCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.queue.closed.Load() {
+		c.queue.dropped.Add(1)
+		return nil
+	}
+
+	select {
+	case c.queue.records <- otlpRecord{core: c.inner, ent: ent, fields: snapshotFields(fields)}:
+	default:
+		c.queue.dropped.Add(1)
+	}
+
+	return nil
+}
+
+func (c *OTLPCore) Sync() error {
+	return nil
+}
+
+// Close stops accepting entries and hands the queued ones to the provider,
+// giving up when ctx is done.
+func (c *OTLPCore) Close(ctx context.Context) error {
+	q := c.queue
+	q.once.Do(func() {
+		q.closed.Store(true)
+		close(q.done)
+	})
+
+	select {
+	case <-q.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *otlpQueue) run() {
+	defer close(q.stopped)
+
+	for {
+		select {
+		case r := <-q.records:
+			r.core.Write(r.ent, r.fields)
+		case <-q.done:
+			for {
+				select {
+				case r := <-q.records:
+					r.core.Write(r.ent, r.fields)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// snapshotFields encodes fields that reference caller-owned values, such as
+// objects, arrays and reflected values, so the background goroutine never
+// reads them after the log call returned. A context.Context, from which
+// otelzap takes the trace and span IDs, is immutable and passed through.
+func snapshotFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		if _, ok := f.Interface.(context.Context); ok {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+
+		switch f.Type {
+		case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType,
+			zapcore.ReflectType, zapcore.StringerType, zapcore.ByteStringType,
+			zapcore.BinaryType, zapcore.InlineMarshalerType:
+		default:
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+
+		if out == nil {
+			out = append(make([]zapcore.Field, 0, len(fields)), fields[:i]...)
+		}
+
+		switch f.Type {
+		case zapcore.BinaryType:
+			out = append(out, zap.Binary(f.Key, slices.Clone(f.Interface.([]byte))))
+			continue
+		case zapcore.ReflectType:
+			out = append(out, zap.Any(f.Key, snapshotReflected(f.Interface)))
+			continue
+		case zapcore.InlineMarshalerType:
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			for k, v := range enc.Fields {
+				out = append(out, zap.Any(k, v))
+			}
+			continue
+		}
+
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		out = append(out, zap.Any(f.Key, enc.Fields[f.Key]))
+	}
+
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// snapshotReflected copies v into plain maps, slices and scalars through a
+// JSON round trip, falling back to its fmt representation.
+func snapshotReflected(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return string(b)
+	}
+	return out
+}
@@ -0,0 +1,435 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const redacted = "[REDACTED]"
+
+// Masker turns a sensitive value into what gets logged instead.
+type Masker func(value string) string
+
+// MaskAll replaces the whole value.
+func MaskAll(string) string {
+	return redacted
+}
+
+// MaskEmail keeps the first character of the local part and the domain,
+// e.g. john.doe@example.com becomes j***@example.com.
+func MaskEmail(value string) string {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok || local == "" {
+		return redacted
+	}
+	return local[:1] + "***@" + domain
+}
+
+// MaskTrailing keeps only the last four digits, e.g. for phone and card
+// numbers.
+func MaskTrailing(value string) string {
+	var digits []rune
+	for _, r := range value {
+		if unicode.IsDigit(r) {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) <= 4 {
+		return redacted
+	}
+	return "***" + string(digits[len(digits)-4:])
+}
+
+// KeyRule masks every field whose key matches Key. Keys are compared
+// ignoring case and punctuation, so "tax_id" also matches "TaxID".
+type KeyRule struct {
+	Key  string
+	Mask Masker
+}
+
+// PatternRule masks every match of Pattern inside string values and log
+// messages, whatever the field key.
+type PatternRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Mask    Masker
+}
+
+type RedactionConfig struct {
+	Keys     []KeyRule
+	Patterns []PatternRule
+}
+
+var (
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// DefaultRedactionConfig covers the credentials, tokens and personal data
+// stored by this schema: passwords, JWTs, reset and verification tokens,
+// TOTP secrets, emails, phone numbers and merchant tax IDs.
+func DefaultRedactionConfig() RedactionConfig {
+	var keys []KeyRule
+	for _, key := range []string{
+		"password", "new_password", "current_password", "confirm_password",
+		"token", "access_token", "refresh_token", "reset_token", "id_token",
+		"challenge_token", "verification_token", "verification_code",
+		"authorization", "cookie", "secret", "client_secret", "totp_secret",
+		"otp", "totp_code", "recovery_code", "code_hash", "code_verifier",
+		"tax_id",
+	} {
+		keys = append(keys, KeyRule{Key: key, Mask: MaskAll})
+	}
+	for _, key := range []string{"email", "contact_email"} {
+		keys = append(keys, KeyRule{Key: key, Mask: MaskEmail})
+	}
+	for _, key := range []string{"phone", "phone_number", "contact_phone"} {
+		keys = append(keys, KeyRule{Key: key, Mask: MaskTrailing})
+	}
+
+	return RedactionConfig{
+		Keys: keys,
+		Patterns: []PatternRule{
+			{Name: "jwt", Pattern: jwtPattern, Mask: MaskAll},
+			{Name: "card", Pattern: cardPattern, Mask: maskCard},
+			{Name: "email", Pattern: emailPattern, Mask: MaskEmail},
+		},
+	}
+}
+
+// redactionConfigFromViper extends the defaults with LOG_REDACT_KEYS, a
+// comma separated list of extra keys masked in full. LOG_REDACT_DISABLED
+// turns redaction off.
+func redactionConfigFromViper() (RedactionConfig, bool) {
+	if viper.GetBool("LOG_REDACT_DISABLED") {
+		return RedactionConfig{}, false
+	}
+
+	cfg := DefaultRedactionConfig()
+	for _, key := range strings.Split(viper.GetString("LOG_REDACT_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			cfg.Keys = append(cfg.Keys, KeyRule{Key: key, Mask: MaskAll})
+		}
+	}
+
+	return cfg, true
+}
+
+// maskCard only masks digit runs that pass the Luhn check, so order and
+// reference numbers of the same length are left alone.
+func maskCard(value string) string {
+	if !luhnValid(value) {
+		return value
+	}
+	return MaskTrailing(value)
+}
+
+func luhnValid(value string) bool {
+	sum, n := 0, 0
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+type redactor struct {
+	keys     map[string]Masker
+	patterns []PatternRule
+}
+
+func newRedactor(cfg RedactionConfig) *redactor {
+	r := &redactor{
+		keys:     make(map[string]Masker, len(cfg.Keys)),
+		patterns: cfg.Patterns,
+	}
+	for _, rule := range cfg.Keys {
+		r.keys[normalizeKey(rule.Key)] = rule.Mask
+	}
+	return r
+}
+
+func normalizeKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+func (r *redactor) keyMask(key string) (Masker, bool) {
+	mask, ok := r.keys[normalizeKey(key)]
+	return mask, ok
+}
+
+func (r *redactor) scrub(value string) string {
+	for _, p := range r.patterns {
+		value = p.Pattern.ReplaceAllStringFunc(value, p.Mask)
+	}
+	return value
+}
+
+func (r *redactor) field(f zapcore.Field) zapcore.Field {
+	if mask, ok := r.keyMask(f.Key); ok {
+		switch f.Type {
+		case zapcore.StringType:
+			return zap.String(f.Key, mask(f.String))
+		case zapcore.ByteStringType:
+			return zap.String(f.Key, mask(string(f.Interface.([]byte))))
+		case zapcore.StringerType:
+			return zap.String(f.Key, mask(fmt.Sprint(f.Interface)))
+		default:
+			return zap.String(f.Key, redacted)
+		}
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.scrub(f.String)
+	case zapcore.ByteStringType:
+		if s := string(f.Interface.([]byte)); r.scrub(s) != s {
+			return zap.String(f.Key, r.scrub(s))
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			if s := r.scrub(err.Error()); s != err.Error() {
+				return zap.String(f.Key, s)
+			}
+		}
+	case zapcore.StringerType:
+		return zap.String(f.Key, r.scrub(fmt.Sprint(f.Interface)))
+	case zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+		f.Interface = redactedObject{r: r, obj: f.Interface.(zapcore.ObjectMarshaler)}
+	case zapcore.ArrayMarshalerType:
+		f.Interface = redactedArray{r: r, arr: f.Interface.(zapcore.ArrayMarshaler)}
+	case zapcore.ReflectType:
+		if _, ok := f.Interface.(context.Context); ok {
+			return f
+		}
+		value, ok := r.reflected(f.Interface)
+		if !ok {
+			return zap.String(f.Key, redacted)
+		}
+		return zap.Any(f.Key, value)
+	}
+
+	return f
+}
+
+// reflected converts a value logged with zap.Any or zap.Reflect, such as a
+// request struct, to plain maps and slices through its JSON form and masks
+// that the way it masks fields. It reports false when value cannot be
+// encoded as JSON.
+func (r *redactor) reflected(value interface{}) (interface{}, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, false
+	}
+
+	return r.value(decoded), true
+}
+
+func (r *redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if mask, ok := r.keyMask(key); ok {
+				v[key] = maskValue(mask, item)
+				continue
+			}
+			v[key] = r.value(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.value(item)
+		}
+	case string:
+		return r.scrub(v)
+	}
+	return v
+}
+
+func maskValue(mask Masker, v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return mask(v)
+	case json.Number:
+		return mask(v.String())
+	default:
+		return redacted
+	}
+}
+
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	if len(fields) == 0 {
+		return fields
+	}
+
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = r.field(f)
+	}
+	return out
+}
+
+// redactedObject applies the key rules to the fields of a nested object
+// such as zap.Object("user", u).
+type redactedObject struct {
+	r   *redactor
+	obj zapcore.ObjectMarshaler
+}
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.obj.MarshalLogObject(&redactingEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+type redactingEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+func (e *redactingEncoder) AddString(key, value string) {
+	if mask, ok := e.r.keyMask(key); ok {
+		e.ObjectEncoder.AddString(key, mask(value))
+		return
+	}
+	e.ObjectEncoder.AddString(key, e.r.scrub(value))
+}
+
+func (e *redactingEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *redactingEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if _, ok := e.r.keyMask(key); ok {
+		e.ObjectEncoder.AddString(key, redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactedObject{r: e.r, obj: obj})
+}
+
+func (e *redactingEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	if _, ok := e.r.keyMask(key); ok {
+		e.ObjectEncoder.AddString(key, redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, redactedArray{r: e.r, arr: arr})
+}
+
+func (e *redactingEncoder) AddReflected(key string, value interface{}) error {
+	if _, ok := e.r.keyMask(key); ok {
+		e.ObjectEncoder.AddString(key, redacted)
+		return nil
+	}
+
+	masked, ok := e.r.reflected(value)
+	if !ok {
+		e.ObjectEncoder.AddString(key, redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, masked)
+}
+
+// redactedArray scrubs the elements of an array such as zap.Strings and
+// applies the key rules to the objects in it, e.g. in zap.Objects.
+type redactedArray struct {
+	r   *redactor
+	arr zapcore.ArrayMarshaler
+}
+
+func (a redactedArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.arr.MarshalLogArray(&redactingArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+type redactingArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redactor
+}
+
+func (e *redactingArrayEncoder) AppendString(value string) {
+	e.ArrayEncoder.AppendString(e.r.scrub(value))
+}
+
+func (e *redactingArrayEncoder) AppendByteString(value []byte) {
+	e.AppendString(string(value))
+}
+
+func (e *redactingArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactedObject{r: e.r, obj: obj})
+}
+
+func (e *redactingArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactedArray{r: e.r, arr: arr})
+}
+
+func (e *redactingArrayEncoder) AppendReflected(value interface{}) error {
+	masked, ok := e.r.reflected(value)
+	if !ok {
+		e.ArrayEncoder.AppendString(redacted)
+		return nil
+	}
+	return e.ArrayEncoder.AppendReflected(masked)
+}
+
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+// NewRedactingCore masks sensitive values before core encodes them: fields
+// whose key matches a KeyRule, and PatternRule matches inside string fields,
+// error messages and the log message itself. Objects, arrays, structs and
+// maps are masked key by key at every level.
+func NewRedactingCore(core zapcore.Core, cfg RedactionConfig) zapcore.Core {
+	return &redactCore{Core: core, r: newRedactor(cfg)}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.scrub(ent.Message)
+	return c.Core.Write(ent, c.r.fields(fields))
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRedactingCoreMasksStructFields(t *testing.T) {
+	type address struct {
+		Phone string
+	}
+	type loginRequest struct {
+		Email    string
+		Password string
+		Note     string
+		Address  address
+	}
+
+	var buf bytes.Buffer
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(&buf),
+		zapcore.DebugLevel,
+	)
+	log := zap.New(NewRedactingCore(core, DefaultRedactionConfig()))
+
+	log.Info("login request", zap.Any("request", loginRequest{
+		Email:    "alice@example.com",
+		Password: "hunter2",
+		Note:     "call bob@example.com",
+		Address:  address{Phone: "+62 812 3456 7890"},
+	}))
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "alice@example.com", "bob@example.com", "812 3456"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("output contains %q: %s", secret, out)
+		}
+	}
+	for _, masked := range []string{`"Password":"[REDACTED]"`, `"Email":"a***@example.com"`, `"Phone":"***7890"`} {
+		if !strings.Contains(out, masked) {
+			t.Errorf("output misses %s: %s", masked, out)
+		}
+	}
+}
+
+type testUser struct {
+	Name     string
+	Password string
+	Tags     []string
+}
+
+func (u testUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	enc.AddString("password", u.Password)
+	return enc.AddArray("tags", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, tag := range u.Tags {
+			arr.AppendString(tag)
+		}
+		return nil
+	}))
+}
+
+type testStringer string
+
+func (s testStringer) String() string {
+	return string(s)
+}
+
+func TestRedactingCoreMasksArraysObjectsAndStringers(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(&buf),
+		zapcore.DebugLevel,
+	)
+	log := zap.New(NewRedactingCore(core, DefaultRedactionConfig()))
+
+	log.Info("request",
+		zap.Strings("password", []string{"hunter2", "hunter3"}),
+		zap.Strings("recipients", []string{"carol@example.com"}),
+		zap.Objects("users", []testUser{{Name: "dave", Password: "s3cret", Tags: []string{"erin@example.com"}}}),
+		zap.Stringer("contact", testStringer("mail frank@example.com")),
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "hunter3", "carol@example.com", "s3cret", "erin@example.com", "frank@example.com"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("output contains %q: %s", secret, out)
+		}
+	}
+	for _, masked := range []string{
+		`"password":"[REDACTED]"`,
+		`"recipients":["c***@example.com"]`,
+		`"users":[{"name":"dave","password":"[REDACTED]","tags":["e***@example.com"]}]`,
+		`"contact":"mail f***@example.com"`,
+	} {
+		if !strings.Contains(out, masked) {
+			t.Errorf("output misses %s: %s", masked, out)
+		}
+	}
+}
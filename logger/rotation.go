@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// RotationConfig controls the log file written next to stdout. Size-based
+// rotation happens on write; Interval adds time-based rotation on top,
+// aligned to local midnight, so 24h rotates at every midnight and 1h at
+// the top of every hour, whenever the process started.
+type RotationConfig struct {
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+	Interval   time.Duration
+}
+
+// rotationConfigFromViper reads LOG_MAX_SIZE_MB, LOG_MAX_BACKUPS,
+// LOG_MAX_AGE_DAYS, LOG_COMPRESS and LOG_ROTATE_INTERVAL. A zero interval
+// disables time-based rotation.
+func rotationConfigFromViper() RotationConfig {
+	cfg := RotationConfig{
+		MaxSizeMB:  100,
+		MaxBackups: 7,
+		MaxAgeDays: 28,
+		Compress:   true,
+		Interval:   24 * time.Hour,
+	}
+
+	if v := viper.GetInt("LOG_MAX_SIZE_MB"); v > 0 {
+		cfg.MaxSizeMB = v
+	}
+	if viper.IsSet("LOG_MAX_BACKUPS") {
+		cfg.MaxBackups = viper.GetInt("LOG_MAX_BACKUPS")
+	}
+	if viper.IsSet("LOG_MAX_AGE_DAYS") {
+		cfg.MaxAgeDays = viper.GetInt("LOG_MAX_AGE_DAYS")
+	}
+	if viper.IsSet("LOG_COMPRESS") {
+		cfg.Compress = viper.GetBool("LOG_COMPRESS")
+	}
+	if viper.IsSet("LOG_ROTATE_INTERVAL") {
+		cfg.Interval = viper.GetDuration("LOG_ROTATE_INTERVAL")
+	}
+
+	return cfg
+}
+
+// rotatingFile writes to path through lumberjack. It also rotates every
+// Interval and on SIGHUP; since lumberjack reopens path after rotating, a
+// SIGHUP sent by logrotate after moving the file makes us write to a fresh
+// one.
+//
+// lumberjack supports a single writer per file, so loggers writing to the
+// same path share one rotatingFile, counted in openFiles.
+type rotatingFile struct {
+	*lumberjack.Logger
+	key  string
+	refs int
+
+	mu       sync.Mutex
+	interval time.Duration
+	timer    *time.Timer
+	closed   bool
+}
+
+var openFiles = struct {
+	sync.Mutex
+	byPath map[string]*rotatingFile
+}{byPath: make(map[string]*rotatingFile)}
+
+// watchHangup starts the single SIGHUP handler that rotates every open file.
+// It stays installed for the rest of the process once a log file has been
+// opened, so a SIGHUP arriving after the files are closed is ignored rather
+// than terminating the process.
+var watchHangup = sync.OnceFunc(func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			rotateOpenFiles()
+		}
+	}()
+})
+
+func rotateOpenFiles() {
+	openFiles.Lock()
+	files := make([]*rotatingFile, 0, len(openFiles.byPath))
+	for _, f := range openFiles.byPath {
+		files = append(files, f)
+	}
+	openFiles.Unlock()
+
+	for _, f := range files {
+		f.rotate(false)
+	}
+}
+
+// openRotatingFile returns the rotatingFile for path, creating it with cfg
+// when no logger has it open yet. Every call must be paired with Close.
+func openRotatingFile(path string, cfg RotationConfig) *rotatingFile {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = filepath.Clean(path)
+	}
+
+	watchHangup()
+
+	openFiles.Lock()
+	defer openFiles.Unlock()
+
+	if f, ok := openFiles.byPath[key]; ok {
+		f.refs++
+		return f
+	}
+
+	f := newRotatingFile(path, cfg)
+	f.key = key
+	f.refs = 1
+	openFiles.byPath[key] = f
+
+	return f
+}
+
+func newRotatingFile(path string, cfg RotationConfig) *rotatingFile {
+	f := &rotatingFile{
+		Logger: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+		},
+		interval: cfg.Interval,
+	}
+
+	if f.interval > 0 {
+		f.mu.Lock()
+		f.timer = time.AfterFunc(time.Until(nextRotation(time.Now(), f.interval)), func() {
+			f.rotate(true)
+		})
+		f.mu.Unlock()
+	}
+
+	return f
+}
+
+// rotate rotates the file unless it was closed meanwhile, which would
+// reopen it. A scheduled rotation also arms the timer for the next one.
+func (f *rotatingFile) rotate(scheduled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+
+	f.Rotate()
+	if scheduled {
+		f.timer.Reset(time.Until(nextRotation(time.Now(), f.interval)))
+	}
+}
+
+// nextRotation returns the first multiple of interval after now, counted
+// from the local midnight that starts now's day.
+func nextRotation(now time.Time, interval time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	elapsed := now.Sub(midnight)
+
+	return midnight.Add((elapsed/interval + 1) * interval)
+}
+
+// Close gives up one reference to the file; the last one stops rotation
+// and closes it.
+func (f *rotatingFile) Close() error {
+	openFiles.Lock()
+	f.refs--
+	last := f.refs <= 0
+	if last && openFiles.byPath[f.key] == f {
+		delete(openFiles.byPath, f.key)
+	}
+	openFiles.Unlock()
+
+	if !last {
+		return nil
+	}
+
+	f.mu.Lock()
+	f.closed = true
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	f.mu.Unlock()
+
+	return f.Logger.Close()
+}
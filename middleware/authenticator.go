@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MamangRust/monolith-ecommerce-pkg/auth"
+	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+	"github.com/MamangRust/monolith-ecommerce-pkg/rbac"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid bearer token")
+	ErrForbidden    = errors.New("insufficient role")
+)
+
+// TokenParser verifies an access token and returns its claims; *auth.Manager
+// implements it.
+type TokenParser interface {
+	ParseTokenContext(ctx context.Context, tokenString string, opts ...auth.ValidateOption) (*auth.Claims, error)
+}
+
+type RoleResolver interface {
+	GetUserRoleNames(ctx context.Context, userID int32) ([]string, error)
+}
+
+type Option func(*Authenticator)
+
+func WithValidateOptions(opts ...auth.ValidateOption) Option {
+	return func(a *Authenticator) {
+		a.validateOpts = append(a.validateOpts, opts...)
+	}
+}
+
+// WithRoleResolver loads the current roles from users_role on every request
+// instead of trusting the roles embedded in the token, so a revoked role
+// takes effect before the token expires.
+func WithRoleResolver(resolver RoleResolver) Option {
+	return func(a *Authenticator) {
+		a.roles = resolver
+	}
+}
+
+// Authenticator turns a bearer token into claims stored on the request
+// context. It is shared by the Echo middleware and the gRPC interceptors.
+type Authenticator struct {
+	tokens       TokenParser
+	validateOpts []auth.ValidateOption
+	roles        RoleResolver
+}
+
+func NewAuthenticator(tokens TokenParser, opts ...Option) *Authenticator {
+	a := &Authenticator{tokens: tokens}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, accessToken string) (context.Context, error) {
+	if accessToken == "" {
+		return ctx, ErrMissingToken
+	}
+
+	claims, err := a.tokens.ParseTokenContext(ctx, accessToken, a.validateOpts...)
+	if errors.Is(err, auth.ErrRevocationUnavailable) {
+		return ctx, err
+	}
+	if err != nil {
+		return ctx, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if a.roles != nil {
+		roles, err := a.roles.GetUserRoleNames(ctx, int32(claims.UserID))
+		if err != nil {
+			return ctx, fmt.Errorf("failed to load user roles: %w", err)
+		}
+		claims.Roles = roles
+	}
+
+	ctx = logger.ContextWithUserID(ContextWithClaims(ctx, claims), claims.UserID)
+
+	return rbac.WithRequestCache(ctx), nil
+}
+
+// Authorize succeeds when the caller holds at least one of roles. An empty
+// list only requires an authenticated caller.
+func Authorize(ctx context.Context, roles []string) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return ErrMissingToken
+	}
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	for _, want := range roles {
+		for _, have := range claims.Roles {
+			if strings.EqualFold(want, have) {
+				return nil
+			}
+		}
+	}
+
+	return ErrForbidden
+}
+
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
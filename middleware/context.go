@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/MamangRust/monolith-ecommerce-pkg/auth"
+)
+
+type claimsKey struct{}
+
+func ContextWithClaims(ctx context.Context, claims *auth.Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*auth.Claims)
+	return claims, ok && claims != nil
+}
+
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return claims.UserID, true
+}
+
+func RolesFromContext(ctx context.Context) []string {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return claims.Roles
+}
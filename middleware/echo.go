@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Echo authenticates every request not matched by skipper and stores the
+// claims on the request context. Requests without a valid token get 401.
+func (a *Authenticator) Echo(skipper func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skipper != nil && skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			ctx, err := a.Authenticate(req.Context(), bearerToken(req.Header.Get(echo.HeaderAuthorization)))
+			if err != nil {
+				return echoError(c, err)
+			}
+
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// RequireRoles guards a route or group; it must run after Authenticator.Echo.
+func RequireRoles(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := Authorize(c.Request().Context(), roles); err != nil {
+				return echoError(c, err)
+			}
+			return next(c)
+		}
+	}
+}
+
+func echoError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrForbidden):
+		return echo.NewHTTPError(http.StatusForbidden, "forbidden").SetInternal(err)
+	case errors.Is(err, ErrMissingToken), errors.Is(err, ErrInvalidToken):
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized").SetInternal(err)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal server error").SetInternal(err)
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MethodPolicy declares per-RPC requirements. Keys are full method names
+// ("/pb.ProductService/CreateProduct") or service wildcards
+// ("/pb.ProductService/*"). An entry for the full method name, public or
+// not, takes precedence over the service wildcard, so Require on one method
+// still applies inside a Public service. Methods without an entry only
+// require an authenticated caller.
+type MethodPolicy struct {
+	public map[string]struct{}
+	roles  map[string][]string
+}
+
+func NewMethodPolicy() *MethodPolicy {
+	return &MethodPolicy{
+		public: make(map[string]struct{}),
+		roles:  make(map[string][]string),
+	}
+}
+
+// Public lets methods be called without a token. It replaces a Require
+// for the same key.
+func (p *MethodPolicy) Public(methods ...string) *MethodPolicy {
+	for _, method := range methods {
+		p.public[method] = struct{}{}
+		delete(p.roles, method)
+	}
+	return p
+}
+
+// Require restricts method to callers with one of roles, or to any
+// authenticated caller when roles is empty. It replaces a Public for the
+// same key.
+func (p *MethodPolicy) Require(method string, roles ...string) *MethodPolicy {
+	p.roles[method] = roles
+	delete(p.public, method)
+	return p
+}
+
+// rule resolves fullMethod against its own entry first and the service
+// wildcard second.
+func (p *MethodPolicy) rule(fullMethod string) (public bool, roles []string) {
+	for _, key := range []string{fullMethod, serviceWildcard(fullMethod)} {
+		if _, ok := p.public[key]; ok {
+			return true, nil
+		}
+		if roles, ok := p.roles[key]; ok {
+			return false, roles
+		}
+	}
+	return false, nil
+}
+
+func serviceWildcard(fullMethod string) string {
+	i := strings.LastIndex(fullMethod, "/")
+	if i < 0 {
+		return fullMethod
+	}
+	return fullMethod[:i+1] + "*"
+}
+
+func (a *Authenticator) UnaryServerInterceptor(policy *MethodPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorizeRPC(ctx, policy, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *Authenticator) StreamServerInterceptor(policy *MethodPolicy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorizeRPC(ss.Context(), policy, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a *Authenticator) authorizeRPC(ctx context.Context, policy *MethodPolicy, fullMethod string) (context.Context, error) {
+	if policy == nil {
+		policy = NewMethodPolicy()
+	}
+
+	public, roles := policy.rule(fullMethod)
+	if public {
+		return ctx, nil
+	}
+
+	ctx, err := a.Authenticate(ctx, tokenFromMetadata(ctx))
+	if err != nil {
+		return ctx, grpcError(err)
+	}
+
+	if err := Authorize(ctx, roles); err != nil {
+		return ctx, grpcError(err)
+	}
+
+	return ctx, nil
+}
+
+func tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+
+	return bearerToken(values[0])
+}
+
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrForbidden):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, ErrMissingToken), errors.Is(err, ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
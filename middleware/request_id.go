@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/MamangRust/monolith-ecommerce-pkg/logger"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const requestIDMetadataKey = "x-request-id"
+
+// RequestID propagates the X-Request-ID header, generating one when absent,
+// and stores it on the request context so logger.FromContext includes it.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if id == "" {
+				id = c.Response().Header().Get(echo.HeaderXRequestID)
+			}
+			if id == "" {
+				id = uuid.NewString()
+			}
+
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.SetRequest(req.WithContext(logger.ContextWithRequestID(req.Context(), id)))
+
+			return next(c)
+		}
+	}
+}
+
+// UnaryRequestIDInterceptor is the gRPC counterpart of RequestID, reading
+// the x-request-id metadata key.
+func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	return logger.ContextWithRequestID(ctx, id)
+}
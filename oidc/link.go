@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	db "github.com/MamangRust/monolith-ecommerce-pkg/database/schema"
+)
+
+var (
+	ErrEmailNotVerified = errors.New("oidc: provider did not verify the email address")
+	ErrIdentityConflict = errors.New("oidc: email belongs to an unverified account")
+)
+
+type IdentityStore interface {
+	GetUserIdentity(ctx context.Context, arg db.GetUserIdentityParams) (*db.UserIdentity, error)
+	CreateUserIdentity(ctx context.Context, arg db.CreateUserIdentityParams) (*db.UserIdentity, error)
+	GetUserByID(ctx context.Context, userID int32) (*db.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*db.User, error)
+	CreateUser(ctx context.Context, arg db.CreateUserParams) (*db.User, error)
+}
+
+// Linker maps verified ID tokens to rows in users.
+type Linker struct {
+	store IdentityStore
+}
+
+func NewLinker(store IdentityStore) *Linker {
+	return &Linker{store: store}
+}
+
+// Resolve returns the user for claims from provider, in order:
+//   - the user already linked to the provider subject;
+//   - the verified user with the same email, which gets linked;
+//   - a new, already verified user without a usable password.
+//
+// Linking and creation require the provider to vouch for the email. An
+// unverified local account with the same email is never linked, since
+// whoever registered it may not own the address.
+func (l *Linker) Resolve(ctx context.Context, provider string, claims *IDTokenClaims) (*db.User, error) {
+	identity, err := l.store.GetUserIdentity(ctx, db.GetUserIdentityParams{
+		Provider: provider,
+		Subject:  claims.Subject,
+	})
+	switch {
+	case err == nil:
+		user, err := l.store.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find linked user: %w", err)
+		}
+		return user, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to find user identity: %w", err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	user, err := l.store.GetUserByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !user.IsVerified.Bool {
+			return nil, ErrIdentityConflict
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		firstname, lastname := claims.GivenName, claims.FamilyName
+		if firstname == "" && lastname == "" {
+			firstname, lastname, _ = strings.Cut(claims.Name, " ")
+		}
+
+		// An empty password hash never matches, so the account can only
+		// sign in through the provider until the user sets a password.
+		user, err = l.store.CreateUser(ctx, db.CreateUserParams{
+			Firstname:  firstname,
+			Lastname:   lastname,
+			Email:      email,
+			Password:   "",
+			IsVerified: sql.NullBool{Bool: true, Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	_, err = l.store.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		UserID:   user.UserID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to link user identity: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,274 @@
+// Package oidctest runs a minimal in-process OpenID Connect provider for
+// exercising oidc.Provider without network access.
+package oidctest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/MamangRust/monolith-ecommerce-pkg/auth"
+	"github.com/MamangRust/monolith-ecommerce-pkg/randomstring"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ClientID     = "oidctest-client"
+	ClientSecret = "oidctest-secret"
+)
+
+// User is the identity the provider signs in as.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+type pendingCode struct {
+	user        User
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// Server approves every authorization request for the current User and
+// enforces PKCE, client credentials and single-use codes like a real
+// provider would.
+type Server struct {
+	URL string
+
+	server *httptest.Server
+	key    *auth.SigningKey
+	keys   *auth.KeyRing
+
+	mu    sync.Mutex
+	user  User
+	codes map[string]pendingCode
+}
+
+func NewServer() (*Server, error) {
+	key, err := auth.GenerateRSAKey("oidctest", 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := auth.NewKeyRing(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		key:   key,
+		keys:  keys,
+		codes: make(map[string]pendingCode),
+		user: User{
+			Subject:       "oidctest-user",
+			Email:         "user@example.com",
+			EmailVerified: true,
+			GivenName:     "Test",
+			FamilyName:    "User",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+
+	return s, nil
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns an HTTP client that trusts the server.
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+func (s *Server) SetUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.user = user
+}
+
+// Login follows authURL the way a browser would after the user consents and
+// returns the state and code delivered to the redirect URL.
+func (s *Server) Login(authURL string) (state string, code string, err error) {
+	client := *s.server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", "", err
+	}
+
+	q := location.Query()
+	return q.Get("state"), q.Get("code"), nil
+}
+
+// SignIDToken signs arbitrary claims with the provider key, for testing
+// rejection of forged or malformed tokens.
+func (s *Server) SignIDToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.key.Method, claims)
+	token.Header["kid"] = s.key.ID
+	return token.SignedString(s.key.Private)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.keys.JWKS())
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("client_id") != ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid client or response_type", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "pkce required", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomstring.GenerateRandomString(32)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.codes[code] = pendingCode{
+		user:        s.user,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+	}
+	s.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != ClientID || clientSecret != ClientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	s.mu.Lock()
+	pending, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok || pending.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != pending.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := s.SignIDToken(jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            pending.user.Subject,
+		"aud":            ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          pending.nonce,
+		"email":          pending.user.Email,
+		"email_verified": pending.user.EmailVerified,
+		"given_name":     pending.user.GivenName,
+		"family_name":    pending.user.FamilyName,
+		"name":           pending.user.GivenName + " " + pending.user.FamilyName,
+	})
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "oidctest-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,312 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MamangRust/monolith-ecommerce-pkg/auth"
+	"github.com/MamangRust/monolith-ecommerce-pkg/randomstring"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidState   = errors.New("oidc: state mismatch")
+	ErrInvalidNonce   = errors.New("oidc: nonce mismatch")
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+)
+
+const (
+	stateLength        = 32
+	nonceLength        = 32
+	codeVerifierLength = 64
+	// jwksRefreshInterval bounds how often an unknown kid triggers a refetch
+	// of the provider keys.
+	jwksRefreshInterval = time.Minute
+)
+
+type Config struct {
+	// Name identifies the provider in user_identities, e.g. "google".
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes defaults to openid, email and profile.
+	Scopes     []string
+	HTTPClient *http.Client
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// AuthRequest is the per-login secret state. Callers keep it server-side or
+// in an encrypted cookie between AuthCodeURL and Exchange.
+type AuthRequest struct {
+	URL          string `json:"-"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// IDTokenClaims are the standard claims this package relies on.
+type IDTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// Provider is an OpenID Connect relying party for one identity provider,
+// using the authorization code flow with PKCE.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	metadata metadata
+
+	mu            sync.Mutex
+	keys          *auth.KeyRing
+	keysFetchedAt time.Time
+}
+
+// NewProvider loads the provider's discovery document and keys.
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("oidc: client id and redirect url are required")
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	p := &Provider{
+		config: config,
+		client: config.HTTPClient,
+	}
+	if p.client == nil {
+		p.client = http.DefaultClient
+	}
+
+	discoveryURL := strings.TrimSuffix(config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, discoveryURL, &p.metadata); err != nil {
+		return nil, fmt.Errorf("failed to load oidc discovery document: %w", err)
+	}
+
+	if p.metadata.Issuer != config.IssuerURL {
+		return nil, fmt.Errorf("oidc: issuer %q does not match configured %q", p.metadata.Issuer, config.IssuerURL)
+	}
+
+	if _, err := p.refreshKeys(ctx, true); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL starts a login. The returned request carries the URL to
+// redirect the browser to and the state, nonce and PKCE verifier Exchange
+// needs later.
+func (p *Provider) AuthCodeURL() (*AuthRequest, error) {
+	state, err := randomstring.GenerateRandomString(stateLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := randomstring.GenerateRandomString(nonceLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	verifier, err := randomstring.GenerateRandomString(codeVerifierLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.config.ClientID)
+	q.Set("redirect_uri", p.config.RedirectURL)
+	q.Set("scope", strings.Join(p.config.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	authURL := p.metadata.AuthorizationEndpoint
+	if strings.Contains(authURL, "?") {
+		authURL += "&" + q.Encode()
+	} else {
+		authURL += "?" + q.Encode()
+	}
+
+	return &AuthRequest{
+		URL:          authURL,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, nil
+}
+
+// Exchange completes a login from the state and code the provider sent to
+// the redirect URL, and returns the verified ID token claims.
+func (p *Provider) Exchange(ctx context.Context, req *AuthRequest, state string, code string) (*IDTokenClaims, error) {
+	if req == nil || req.State == "" || subtle.ConstantTimeCompare([]byte(req.State), []byte(state)) != 1 {
+		return nil, ErrInvalidState
+	}
+	if code == "" {
+		return nil, errors.New("oidc: missing authorization code")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", req.CodeVerifier)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("oidc: token endpoint returned %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+
+	return p.VerifyIDToken(ctx, token.IDToken, req.Nonce)
+}
+
+// VerifyIDToken checks the signature against the provider JWKS, and the
+// issuer, audience, expiry and nonce of rawToken.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken string, nonce string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+
+	_, err := jwt.ParseWithClaims(rawToken, claims, p.keyFunc(ctx),
+		jwt.WithIssuer(p.metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "EdDSA"}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+
+	if nonce != "" && subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrInvalidNonce
+	}
+
+	return claims, nil
+}
+
+// keyFunc resolves the signing key and refetches the JWKS once when the
+// kid is unknown, so provider key rotations are picked up.
+func (p *Provider) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		p.mu.Lock()
+		keys := p.keys
+		p.mu.Unlock()
+
+		key, err := keys.Keyfunc()(token)
+		if !errors.Is(err, auth.ErrKeyNotFound) {
+			return key, err
+		}
+
+		keys, refreshErr := p.refreshKeys(ctx, false)
+		if refreshErr != nil {
+			return nil, refreshErr
+		}
+
+		return keys.Keyfunc()(token)
+	}
+}
+
+func (p *Provider) refreshKeys(ctx context.Context, force bool) (*auth.KeyRing, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !force && time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return p.keys, nil
+	}
+
+	keys, err := auth.FetchJWKS(ctx, p.client, p.metadata.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	return keys, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
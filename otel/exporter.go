@@ -0,0 +1,181 @@
+package otel_pkg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"google.golang.org/grpc/credentials"
+)
+
+// exporterConfig holds the collector connection settings shared by the
+// OTLP exporters of every signal.
+type exporterConfig struct {
+	endpoint string
+	tls      *tls.Config
+	headers  map[string]string
+}
+
+// exporterConfigFromViper reads OTEL_ENDPOINT and OTEL_EXPORTER_HEADERS
+// ("key=value,key=value"). The connection is plaintext, as before, unless
+// OTEL_EXPORTER_INSECURE is false or one of OTEL_EXPORTER_CA_FILE,
+// OTEL_EXPORTER_CERT_FILE and OTEL_EXPORTER_KEY_FILE is set; the last two
+// enable mutual TLS.
+func exporterConfigFromViper() (exporterConfig, error) {
+	cfg := exporterConfig{endpoint: viper.GetString("OTEL_ENDPOINT")}
+
+	headers, err := parseHeaders(viper.GetString("OTEL_EXPORTER_HEADERS"))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.headers = headers
+
+	caFile := viper.GetString("OTEL_EXPORTER_CA_FILE")
+	certFile := viper.GetString("OTEL_EXPORTER_CERT_FILE")
+	keyFile := viper.GetString("OTEL_EXPORTER_KEY_FILE")
+
+	insecure := caFile == "" && certFile == ""
+	if viper.IsSet("OTEL_EXPORTER_INSECURE") {
+		insecure = viper.GetBool("OTEL_EXPORTER_INSECURE")
+	}
+	if insecure {
+		return cfg, nil
+	}
+
+	cfg.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read exporter CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return cfg, fmt.Errorf("no certificates found in exporter CA file %q", caFile)
+		}
+		cfg.tls.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to load exporter client certificate: %w", err)
+		}
+		cfg.tls.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// exporterKindFromViper reads the exporter of one signal from key: grpc
+// (the default), http, stdout or none. An OTLP exporter without
+// OTEL_ENDPOINT has nowhere to send to and is reported as none.
+func exporterKindFromViper(key string, cfg exporterConfig) (string, error) {
+	kind := strings.ToLower(viper.GetString(key))
+
+	switch kind {
+	case "", "grpc":
+		kind = "grpc"
+	case "http", "stdout", "none":
+	default:
+		return "", fmt.Errorf("unknown %s %q", key, kind)
+	}
+
+	if (kind == "grpc" || kind == "http") && cfg.endpoint == "" {
+		return "none", nil
+	}
+
+	return kind, nil
+}
+
+func parseHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_HEADERS entry %q", pair)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+func (c exporterConfig) traceGRPCOptions() []otlptracegrpc.Option {
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(c.endpoint),
+		otlptracegrpc.WithHeaders(c.headers),
+	}
+	if c.tls == nil {
+		return append(opts, otlptracegrpc.WithInsecure())
+	}
+	return append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(c.tls)))
+}
+
+func (c exporterConfig) traceHTTPOptions() []otlptracehttp.Option {
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(c.endpoint),
+		otlptracehttp.WithHeaders(c.headers),
+	}
+	if c.tls == nil {
+		return append(opts, otlptracehttp.WithInsecure())
+	}
+	return append(opts, otlptracehttp.WithTLSClientConfig(c.tls))
+}
+
+func (c exporterConfig) metricGRPCOptions() []otlpmetricgrpc.Option {
+	opts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithEndpoint(c.endpoint),
+		otlpmetricgrpc.WithHeaders(c.headers),
+	}
+	if c.tls == nil {
+		return append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	return append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(c.tls)))
+}
+
+func (c exporterConfig) metricHTTPOptions() []otlpmetrichttp.Option {
+	opts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(c.endpoint),
+		otlpmetrichttp.WithHeaders(c.headers),
+	}
+	if c.tls == nil {
+		return append(opts, otlpmetrichttp.WithInsecure())
+	}
+	return append(opts, otlpmetrichttp.WithTLSClientConfig(c.tls))
+}
+
+func (c exporterConfig) logGRPCOptions() []otlploggrpc.Option {
+	opts := []otlploggrpc.Option{
+		otlploggrpc.WithEndpoint(c.endpoint),
+		otlploggrpc.WithHeaders(c.headers),
+	}
+	if c.tls == nil {
+		return append(opts, otlploggrpc.WithInsecure())
+	}
+	return append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(c.tls)))
+}
+
+func (c exporterConfig) logHTTPOptions() []otlploghttp.Option {
+	opts := []otlploghttp.Option{
+		otlploghttp.WithEndpoint(c.endpoint),
+		otlploghttp.WithHeaders(c.headers),
+	}
+	if c.tls == nil {
+		return append(opts, otlploghttp.WithInsecure())
+	}
+	return append(opts, otlploghttp.WithTLSClientConfig(c.tls))
+}
@@ -0,0 +1,121 @@
+package otel_pkg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/log/noop"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// InitLoggerProvider configures log export from viper and installs the
+// provider globally for logger.NewOTLPCore:
+//   - OTEL_LOGS_EXPORTER: grpc (default), http, stdout or none
+//   - OTEL_ENDPOINT plus the TLS and header settings of exporterConfigFromViper
+//
+// Records carry the same resource as InitTracerProvider and are batched;
+// when the collector is unreachable, exports are retried for
+// OTEL_LOGS_RETRY_MAX (default 30s) and the oldest records are discarded
+// once OTEL_LOGS_MAX_QUEUE_SIZE (default 2048) is reached, without blocking
+// the application.
+//
+// With an OTLP exporter and no OTEL_ENDPOINT, or with none, a no-op provider
+// is installed and the returned shutdown does nothing.
+func InitLoggerProvider(service string, ctx context.Context) (func(context.Context) error, error) {
+	logExporter, err := newLogExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if logExporter == nil {
+		global.SetLoggerProvider(noop.NewLoggerProvider())
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := newResource(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	queueSize := viper.GetInt("OTEL_LOGS_MAX_QUEUE_SIZE")
+	if queueSize <= 0 {
+		queueSize = 2048
+	}
+
+	batchSize := viper.GetInt("OTEL_LOGS_BATCH_SIZE")
+	if batchSize <= 0 || batchSize > queueSize {
+		batchSize = min(512, queueSize)
+	}
+
+	processor := sdklog.NewBatchProcessor(
+		logExporter,
+		sdklog.WithMaxQueueSize(queueSize),
+		sdklog.WithExportMaxBatchSize(batchSize),
+		sdklog.WithExportInterval(time.Second),
+	)
+
+	loggerProvider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(processor),
+	)
+
+	global.SetLoggerProvider(loggerProvider)
+
+	return loggerProvider.Shutdown, nil
+}
+
+// newLogExporter returns nil when log export is disabled.
+func newLogExporter(ctx context.Context) (sdklog.Exporter, error) {
+	cfg, err := exporterConfigFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := exporterKindFromViper("OTEL_LOGS_EXPORTER", cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	retryMax := viper.GetDuration("OTEL_LOGS_RETRY_MAX")
+	if retryMax <= 0 {
+		retryMax = 30 * time.Second
+	}
+
+	var exporter sdklog.Exporter
+	switch kind {
+	case "none":
+		return nil, nil
+	case "stdout":
+		exporter, err = stdoutlog.New()
+	case "http":
+		exporter, err = otlploghttp.New(ctx, append(cfg.logHTTPOptions(),
+			otlploghttp.WithTimeout(10*time.Second),
+			otlploghttp.WithRetry(otlploghttp.RetryConfig{
+				Enabled:         true,
+				InitialInterval: time.Second,
+				MaxInterval:     5 * time.Second,
+				MaxElapsedTime:  retryMax,
+			}),
+		)...)
+	default:
+		exporter, err = otlploggrpc.New(ctx, append(cfg.logGRPCOptions(),
+			otlploggrpc.WithTimeout(10*time.Second),
+			otlploggrpc.WithRetry(otlploggrpc.RetryConfig{
+				Enabled:         true,
+				InitialInterval: time.Second,
+				MaxInterval:     5 * time.Second,
+				MaxElapsedTime:  retryMax,
+			}),
+		)...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log exporter: %w", err)
+	}
+
+	return exporter, nil
+}
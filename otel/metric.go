@@ -0,0 +1,149 @@
+package otel_pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// InitMeterProvider configures metrics from viper and installs the provider
+// globally:
+//   - OTEL_METRICS_EXPORTER: grpc (default), http, stdout or none, pushed
+//     every OTEL_METRICS_INTERVAL (default 15s)
+//   - OTEL_ENDPOINT plus the TLS and header settings of exporterConfigFromViper
+//
+// Go runtime metrics and process/host metrics are registered on it. When
+// OTEL_METRICS_PROMETHEUS is true the returned handler serves the same
+// metrics, plus the Prometheus process collector, for scraping, e.g.
+// e.GET("/metrics", echo.WrapHandler(handler)); otherwise it is nil.
+//
+// With an OTLP exporter and no OTEL_ENDPOINT, or with none, nothing is
+// pushed; unless Prometheus is enabled a no-op provider is installed and
+// the returned shutdown does nothing.
+func InitMeterProvider(service string, ctx context.Context) (func(context.Context) error, http.Handler, error) {
+	metricExporter, err := newMetricExporter(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prom := viper.GetBool("OTEL_METRICS_PROMETHEUS")
+	if metricExporter == nil && !prom {
+		otel.SetMeterProvider(noop.NewMeterProvider())
+		return func(context.Context) error { return nil }, nil, nil
+	}
+
+	res, err := newResource(ctx, service)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	interval := viper.GetDuration("OTEL_METRICS_INTERVAL")
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	opts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	if metricExporter != nil {
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(interval))))
+	}
+
+	var handler http.Handler
+	if prom {
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+			return nil, nil, fmt.Errorf("failed to register process collector: %w", err)
+		}
+
+		promExporter, err := otelprom.New(otelprom.WithRegisterer(registry))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+		}
+
+		opts = append(opts, sdkmetric.WithReader(promExporter))
+		handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(opts...)
+
+	if err := runtime.Start(runtime.WithMeterProvider(meterProvider)); err != nil {
+		meterProvider.Shutdown(ctx)
+		return nil, nil, fmt.Errorf("failed to start runtime metrics: %w", err)
+	}
+
+	if err := host.Start(host.WithMeterProvider(meterProvider)); err != nil {
+		meterProvider.Shutdown(ctx)
+		return nil, nil, fmt.Errorf("failed to start host metrics: %w", err)
+	}
+
+	otel.SetMeterProvider(meterProvider)
+
+	return meterProvider.Shutdown, handler, nil
+}
+
+// newMetricExporter returns nil when pushing metrics is disabled.
+func newMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	cfg, err := exporterConfigFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := exporterKindFromViper("OTEL_METRICS_EXPORTER", cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var exporter sdkmetric.Exporter
+	switch kind {
+	case "none":
+		return nil, nil
+	case "stdout":
+		exporter, err = stdoutmetric.New()
+	case "http":
+		exporter, err = otlpmetrichttp.New(ctx, cfg.metricHTTPOptions()...)
+	default:
+		exporter, err = otlpmetricgrpc.New(ctx, cfg.metricGRPCOptions()...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	return exporter, nil
+}
+
+// InitProviders sets up tracing and metrics for service, each following
+// its own exporter setting and falling back to a no-op provider without
+// OTEL_ENDPOINT. The returned shutdown flushes and stops both, metrics
+// first, and reports every error.
+func InitProviders(service string, ctx context.Context) (func(context.Context) error, http.Handler, error) {
+	shutdownTracer, err := InitTracerProvider(service, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shutdownMeter, handler, err := InitMeterProvider(service, ctx)
+	if err != nil {
+		return nil, nil, errors.Join(err, shutdownTracer(ctx))
+	}
+
+	shutdown := func(ctx context.Context) error {
+		return errors.Join(shutdownMeter(ctx), shutdownTracer(ctx))
+	}
+
+	return shutdown, handler, nil
+}
@@ -2,49 +2,143 @@ package otel_pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/spf13/viper"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
+// InitTracerProvider configures tracing from viper:
+//   - OTEL_TRACES_EXPORTER: grpc (default), http, stdout or none
+//   - OTEL_TRACES_SAMPLER_RATIO: fraction of new traces sampled, default 1;
+//     spans with a parent follow the parent's decision
+//   - OTEL_ENDPOINT plus the TLS and header settings of exporterConfigFromViper
+//
+// With an OTLP exporter and no OTEL_ENDPOINT, or with none, a no-op provider
+// is installed and the returned shutdown does nothing.
 func InitTracerProvider(service string, ctx context.Context) (func(context.Context) error, error) {
-	traceExporter, err := otlptracegrpc.New(
-		ctx,
-		otlptracegrpc.WithInsecure(),
-		otlptracegrpc.WithEndpoint(viper.GetString("OTEL_ENDPOINT")),
-	)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	traceExporter, err := newTraceExporter(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		return nil, err
+	}
+	if traceExporter == nil {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }, nil
 	}
 
-	res, err := resource.New(
-		ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(service),
-			semconv.ServiceVersionKey.String("1.0.0"),
-		),
-	)
+	res, err := newResource(ctx, service)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+		return nil, err
 	}
 
 	tracerProvider := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(traceExporter),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(samplerFromViper()),
 	)
 
 	otel.SetTracerProvider(tracerProvider)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{},
-		propagation.Baggage{},
-	))
 
 	return tracerProvider.Shutdown, nil
 }
+
+// newTraceExporter returns nil when tracing is disabled.
+func newTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	cfg, err := exporterConfigFromViper()
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := exporterKindFromViper("OTEL_TRACES_EXPORTER", cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch kind {
+	case "none":
+		return nil, nil
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "http":
+		exporter, err = otlptracehttp.New(ctx, cfg.traceHTTPOptions()...)
+	default:
+		exporter, err = otlptracegrpc.New(ctx, cfg.traceGRPCOptions()...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	return exporter, nil
+}
+
+func samplerFromViper() sdktrace.Sampler {
+	ratio := 1.0
+	if viper.IsSet("OTEL_TRACES_SAMPLER_RATIO") {
+		ratio = viper.GetFloat64("OTEL_TRACES_SAMPLER_RATIO")
+	}
+
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+}
+
+// newResource describes the service for every signal, so traces, metrics
+// and logs from one process carry the same attributes. SERVICE_VERSION and
+// DEPLOYMENT_ENVIRONMENT (falling back to APP_ENV) are added to what the
+// host, container, OS and process detectors and OTEL_RESOURCE_ATTRIBUTES
+// provide. Command line arguments are left out as they may hold secrets.
+func newResource(ctx context.Context, service string) (*resource.Resource, error) {
+	version := viper.GetString("SERVICE_VERSION")
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	env := viper.GetString("DEPLOYMENT_ENVIRONMENT")
+	if env == "" {
+		env = viper.GetString("APP_ENV")
+	}
+	if env == "" {
+		env = os.Getenv("APP_ENV")
+	}
+	if env == "" {
+		env = "development"
+	}
+
+	res, err := resource.New(
+		ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithContainer(),
+		resource.WithOS(),
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithAttributes(
+			semconv.ServiceName(service),
+			semconv.ServiceVersion(version),
+			semconv.DeploymentEnvironment(env),
+		),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	return res, nil
+}
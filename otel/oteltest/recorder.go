@@ -0,0 +1,247 @@
+// Package oteltest records spans and metrics in memory so tests can assert
+// on the telemetry code emits through the global OpenTelemetry providers.
+package oteltest
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Point is one metric data point. Value is the sum, gauge value or, for
+// histograms, the sum of observations; Count is only set for histograms.
+type Point struct {
+	Attributes attribute.Set
+	Value      float64
+	Count      uint64
+
+	// cumulative points are reported relative to the last Reset; gauges
+	// are not.
+	cumulative bool
+}
+
+// Recorder owns a tracer and meter provider backed by memory. Spans are
+// available as soon as they end; metrics are collected on every read.
+type Recorder struct {
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+
+	prevTracer     trace.TracerProvider
+	prevMeter      metric.MeterProvider
+	prevPropagator propagation.TextMapPropagator
+
+	mu       sync.Mutex
+	baseline map[string][]Point
+}
+
+// Install creates a Recorder and makes it the global tracer and meter
+// provider, with the W3C trace context and baggage propagators. Call Close
+// when the test ends to restore the previous globals:
+//
+//	rec := oteltest.Install()
+//	defer rec.Close()
+//
+// Instruments bind to the provider that is global when they are created,
+// so install the Recorder before constructing the code under test and use
+// Reset, not a new Recorder, between subtests.
+func Install() *Recorder {
+	r := &Recorder{
+		spans:          tracetest.NewInMemoryExporter(),
+		reader:         sdkmetric.NewManualReader(),
+		prevTracer:     otel.GetTracerProvider(),
+		prevMeter:      otel.GetMeterProvider(),
+		prevPropagator: otel.GetTextMapPropagator(),
+		baseline:       make(map[string][]Point),
+	}
+
+	r.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(r.spans),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+	r.meterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(r.reader))
+
+	otel.SetTracerProvider(r.tracerProvider)
+	otel.SetMeterProvider(r.meterProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return r
+}
+
+// Close shuts the providers down and reinstates the globals that were set
+// before Install.
+func (r *Recorder) Close() {
+	ctx := context.Background()
+	r.tracerProvider.Shutdown(ctx)
+	r.meterProvider.Shutdown(ctx)
+
+	otel.SetTracerProvider(r.prevTracer)
+	otel.SetMeterProvider(r.prevMeter)
+	otel.SetTextMapPropagator(r.prevPropagator)
+}
+
+func (r *Recorder) TracerProvider() trace.TracerProvider {
+	return r.tracerProvider
+}
+
+func (r *Recorder) MeterProvider() metric.MeterProvider {
+	return r.meterProvider
+}
+
+// Reset forgets the spans ended so far and makes metric reads start from
+// zero again.
+func (r *Recorder) Reset() {
+	r.spans.Reset()
+
+	baseline := r.collect()
+
+	r.mu.Lock()
+	r.baseline = baseline
+	r.mu.Unlock()
+}
+
+// Spans returns every ended span in the order they ended.
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	return r.spans.GetSpans()
+}
+
+// FindSpans returns the ended spans called name that carry all of attrs.
+func (r *Recorder) FindSpans(name string, attrs ...attribute.KeyValue) tracetest.SpanStubs {
+	var found tracetest.SpanStubs
+	for _, span := range r.spans.GetSpans() {
+		if span.Name == name && hasAttributes(attribute.NewSet(span.Attributes...), attrs) {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+// FindSpan returns the first span FindSpans would return.
+func (r *Recorder) FindSpan(name string, attrs ...attribute.KeyValue) (tracetest.SpanStub, bool) {
+	found := r.FindSpans(name, attrs...)
+	if len(found) == 0 {
+		return tracetest.SpanStub{}, false
+	}
+	return found[0], true
+}
+
+// Points returns the data points of the metric called name recorded since
+// Install or the last Reset.
+func (r *Recorder) Points(name string) []Point {
+	points := r.collect()[name]
+
+	r.mu.Lock()
+	baseline := r.baseline[name]
+	r.mu.Unlock()
+
+	for i, p := range points {
+		if !p.cumulative {
+			continue
+		}
+		for _, b := range baseline {
+			if p.Attributes.Equals(&b.Attributes) {
+				points[i].Value -= b.Value
+				points[i].Count -= b.Count
+				break
+			}
+		}
+	}
+
+	return points
+}
+
+// Value adds up the Value of the points of name that carry all of attrs,
+// e.g. the total of a counter for one operation.
+func (r *Recorder) Value(name string, attrs ...attribute.KeyValue) float64 {
+	var total float64
+	for _, p := range r.Points(name) {
+		if hasAttributes(p.Attributes, attrs) {
+			total += p.Value
+		}
+	}
+	return total
+}
+
+// Count adds up the Count of the histogram points of name that carry all
+// of attrs.
+func (r *Recorder) Count(name string, attrs ...attribute.KeyValue) uint64 {
+	var total uint64
+	for _, p := range r.Points(name) {
+		if hasAttributes(p.Attributes, attrs) {
+			total += p.Count
+		}
+	}
+	return total
+}
+
+func (r *Recorder) collect() map[string][]Point {
+	var rm metricdata.ResourceMetrics
+	if err := r.reader.Collect(context.Background(), &rm); err != nil {
+		otel.Handle(err)
+	}
+
+	points := make(map[string][]Point)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points[m.Name] = append(points[m.Name], toPoints(m.Data)...)
+		}
+	}
+	return points
+}
+
+func toPoints(data metricdata.Aggregation) []Point {
+	var points []Point
+
+	switch d := data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range d.DataPoints {
+			points = append(points, Point{Attributes: dp.Attributes, Value: float64(dp.Value), cumulative: true})
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range d.DataPoints {
+			points = append(points, Point{Attributes: dp.Attributes, Value: dp.Value, cumulative: true})
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range d.DataPoints {
+			points = append(points, Point{Attributes: dp.Attributes, Value: float64(dp.Value)})
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range d.DataPoints {
+			points = append(points, Point{Attributes: dp.Attributes, Value: dp.Value})
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range d.DataPoints {
+			points = append(points, Point{Attributes: dp.Attributes, Value: float64(dp.Sum), Count: dp.Count, cumulative: true})
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range d.DataPoints {
+			points = append(points, Point{Attributes: dp.Attributes, Value: dp.Sum, Count: dp.Count, cumulative: true})
+		}
+	}
+
+	return points
+}
+
+func hasAttributes(set attribute.Set, attrs []attribute.KeyValue) bool {
+	for _, want := range attrs {
+		got, ok := set.Value(want.Key)
+		if !ok || got != want.Value {
+			return false
+		}
+	}
+	return true
+}
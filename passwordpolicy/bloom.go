@@ -0,0 +1,235 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+var bloomMagic = [4]byte{'P', 'W', 'B', 'F'}
+
+const (
+	bloomVersion = 1
+	// maxBloomBits and maxBloomHashes bound what ReadBloomFilter accepts; a
+	// filter of every known breached password at a 0.1% false positive rate
+	// needs about 1.2e10 bits and 10 hashes.
+	maxBloomBits   = 1 << 36
+	maxBloomHashes = 64
+	bloomChunk     = 1 << 17
+)
+
+type bloomHeader struct {
+	Magic   [4]byte
+	Version uint8
+	K       uint32
+	M       uint64
+}
+
+// BloomFilter is a compact, offline set of breached passwords. Entries are
+// keyed by the SHA-1 of the password so filters can be built directly from
+// the Have I Been Pwned "HASH:count" dumps, see BuildBloomFilterFromSHA1, as
+// well as from plain word lists.
+type BloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint32
+}
+
+func NewBloomFilter(expectedItems int, falsePositiveRate float64) *BloomFilter {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	n := float64(expectedItems)
+	m := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/n*math.Ln2)))
+
+	return &BloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// BuildBloomFilter reads one plain password per line, such as a word list.
+// Every line is hashed, including ones that look like a SHA-1 digest; use
+// BuildBloomFilterFromSHA1 for digest dumps.
+func BuildBloomFilter(r io.Reader, expectedItems int, falsePositiveRate float64) (*BloomFilter, error) {
+	filter := NewBloomFilter(expectedItems, falsePositiveRate)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		filter.Add(line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return filter, nil
+}
+
+// BuildBloomFilterFromSHA1 reads one 40 character SHA-1 hex digest per line,
+// optionally followed by ":count", as in the Have I Been Pwned dumps. Lines
+// that are not a digest are rejected.
+func BuildBloomFilterFromSHA1(r io.Reader, expectedItems int, falsePositiveRate float64) (*BloomFilter, error) {
+	filter := NewBloomFilter(expectedItems, falsePositiveRate)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		digest, ok := parseSHA1Line(text)
+		if !ok {
+			return nil, fmt.Errorf("line %d of breached password list is not a SHA-1 digest", line)
+		}
+
+		filter.AddSHA1(digest)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return filter, nil
+}
+
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password filter '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat breached password filter '%s': %w", path, err)
+	}
+
+	return readBloomFilter(bufio.NewReader(f), info.Size())
+}
+
+// ReadBloomFilter reads a filter written by WriteTo. Headers asking for
+// more than maxBloomBits bits or maxBloomHashes hashes are rejected, and the
+// bits are read in chunks, so a corrupt header cannot make it allocate more
+// memory than r actually holds.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	return readBloomFilter(r, -1)
+}
+
+// readBloomFilter checks the header against size, the total length of r,
+// when it is known.
+func readBloomFilter(r io.Reader, size int64) (*BloomFilter, error) {
+	var header bloomHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter header: %w", err)
+	}
+
+	if header.Magic != bloomMagic || header.Version != bloomVersion {
+		return nil, errors.New("not a breached password bloom filter")
+	}
+	if header.M == 0 || header.M > maxBloomBits || header.K == 0 || header.K > maxBloomHashes {
+		return nil, errors.New("corrupt bloom filter header")
+	}
+
+	words := (header.M + 63) / 64
+	if size >= 0 && uint64(size-int64(binary.Size(header))) != words*8 {
+		return nil, errors.New("bloom filter size does not match its header")
+	}
+
+	bits := make([]uint64, 0, min(words, bloomChunk))
+	for uint64(len(bits)) < words {
+		chunk := make([]uint64, min(words-uint64(len(bits)), bloomChunk))
+		if err := binary.Read(r, binary.LittleEndian, chunk); err != nil {
+			return nil, fmt.Errorf("failed to read bloom filter bits: %w", err)
+		}
+		bits = append(bits, chunk...)
+	}
+
+	return &BloomFilter{bits: bits, m: header.M, k: header.K}, nil
+}
+
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := bloomHeader{bloomMagic, bloomVersion, f.k, f.m}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(w, binary.LittleEndian, f.bits); err != nil {
+		return int64(binary.Size(header)), err
+	}
+
+	return int64(binary.Size(header) + binary.Size(f.bits)), nil
+}
+
+func (f *BloomFilter) Add(password string) {
+	f.AddSHA1(sha1.Sum([]byte(password)))
+}
+
+// AddSHA1 adds a password known only by its SHA-1 digest.
+func (f *BloomFilter) AddSHA1(digest [sha1.Size]byte) {
+	h1, h2 := splitDigest(digest)
+
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (f *BloomFilter) Contains(password string) bool {
+	digest := sha1.Sum([]byte(password))
+	h1, h2 := splitDigest(digest)
+
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsBreached satisfies BreachedChecker.
+func (f *BloomFilter) IsBreached(password string) (bool, error) {
+	return f.Contains(password), nil
+}
+
+func splitDigest(digest [sha1.Size]byte) (uint64, uint64) {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+	return h1, h2
+}
+
+func parseSHA1Line(line string) ([sha1.Size]byte, bool) {
+	var digest [sha1.Size]byte
+
+	hexPart, _, _ := strings.Cut(line, ":")
+	if len(hexPart) != hex.EncodedLen(sha1.Size) {
+		return digest, false
+	}
+
+	if _, err := hex.Decode(digest[:], []byte(hexPart)); err != nil {
+		return digest, false
+	}
+
+	return digest, true
+}
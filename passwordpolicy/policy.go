@@ -0,0 +1,193 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is the input length bcrypt silently truncates to. Passwords
+// longer than this would verify with any suffix, so they are rejected while
+// bcrypt hashes are still in use.
+const bcryptMaxBytes = 72
+
+// minPersonalInfoLength keeps short names such as "Al" from rejecting half
+// of all passwords.
+const minPersonalInfoLength = 3
+
+type ViolationCode string
+
+const (
+	ViolationTooShort      ViolationCode = "too_short"
+	ViolationTooLong       ViolationCode = "too_long"
+	ViolationMissingUpper  ViolationCode = "missing_upper"
+	ViolationMissingLower  ViolationCode = "missing_lower"
+	ViolationMissingDigit  ViolationCode = "missing_digit"
+	ViolationMissingSymbol ViolationCode = "missing_symbol"
+	ViolationContainsEmail ViolationCode = "contains_email"
+	ViolationContainsName  ViolationCode = "contains_name"
+	ViolationBreached      ViolationCode = "breached"
+)
+
+type Violation struct {
+	Code    ViolationCode `json:"code"`
+	Message string        `json:"message"`
+}
+
+// ValidationError lists every rule a password broke, so the user can fix
+// them all at once instead of one per attempt.
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "password rejected: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Has(code ViolationCode) bool {
+	for _, v := range e.Violations {
+		if v.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// BreachedChecker reports whether a password appears in a list of known
+// leaked passwords.
+type BreachedChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// UserInfo is the personal data a password must not contain.
+type UserInfo struct {
+	Email     string
+	Firstname string
+	Lastname  string
+}
+
+type Policy struct {
+	MinLength     int
+	MaxBytes      int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Breached      BreachedChecker
+}
+
+// DefaultPolicy follows NIST SP 800-63B: length over composition rules, and
+// a check against breached passwords when a checker is configured.
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength: 8,
+		MaxBytes:  bcryptMaxBytes,
+	}
+}
+
+// Validate checks password against the policy and returns a
+// *ValidationError listing the violations, or an error from the breached
+// password checker.
+func (p Policy) Validate(password string, user UserInfo) error {
+	var violations []Violation
+
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		violations = append(violations, Violation{
+			Code:    ViolationTooShort,
+			Message: fmt.Sprintf("password must be at least %d characters", p.MinLength),
+		})
+	}
+
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violations = append(violations, Violation{
+			Code:    ViolationTooLong,
+			Message: fmt.Sprintf("password must be at most %d bytes", p.MaxBytes),
+		})
+	}
+
+	violations = append(violations, p.checkClasses(password)...)
+	violations = append(violations, checkPersonalInfo(password, user)...)
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Code:    ViolationBreached,
+				Message: "password has appeared in a data breach",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+
+	return nil
+}
+
+func (p Policy) checkClasses(password string) []Violation {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	var violations []Violation
+
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{ViolationMissingUpper, "password must contain an uppercase letter"})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{ViolationMissingLower, "password must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{ViolationMissingDigit, "password must contain a digit"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{ViolationMissingSymbol, "password must contain a symbol"})
+	}
+
+	return violations
+}
+
+func checkPersonalInfo(password string, user UserInfo) []Violation {
+	lower := strings.ToLower(password)
+
+	var violations []Violation
+
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	local, _, _ := strings.Cut(email, "@")
+	if containsPart(lower, email) || containsPart(lower, local) {
+		violations = append(violations, Violation{ViolationContainsEmail, "password must not contain your email address"})
+	}
+
+	for _, name := range []string{user.Firstname, user.Lastname} {
+		if containsPart(lower, strings.ToLower(strings.TrimSpace(name))) {
+			violations = append(violations, Violation{ViolationContainsName, "password must not contain your name"})
+			break
+		}
+	}
+
+	return violations
+}
+
+func containsPart(password, part string) bool {
+	return utf8.RuneCountInString(part) >= minPersonalInfoLength && strings.Contains(password, part)
+}
@@ -5,16 +5,29 @@ import (
 	"math/big"
 )
 
-const characters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+const (
+	characters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	digits     = "0123456789"
+)
 
 func GenerateRandomString(length int) (string, error) {
+	return generate(characters, length)
+}
+
+// GenerateNumericCode returns a uniformly random string of decimal digits,
+// suitable for one-time codes typed in by users.
+func GenerateNumericCode(length int) (string, error) {
+	return generate(digits, length)
+}
+
+func generate(alphabet string, length int) (string, error) {
 	result := make([]byte, length)
 	for i := range result {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(characters))))
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
 		if err != nil {
 			return "", err
 		}
-		result[i] = characters[num.Int64()]
+		result[i] = alphabet[num.Int64()]
 	}
 	return string(result), nil
 }
@@ -0,0 +1,38 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+)
+
+type decisionKey struct {
+	userID   int
+	action   string
+	resource Resource
+}
+
+// requestCache memoizes permission lookups and decisions for the lifetime of
+// a single request, so handlers can call Can repeatedly without extra queries.
+type requestCache struct {
+	mu          sync.Mutex
+	permissions map[int][]Permission
+	merchants   map[int][]int
+	decisions   map[decisionKey]bool
+}
+
+type cacheKey struct{}
+
+// WithRequestCache attaches an empty decision cache to ctx. Call it once per
+// incoming request, typically from the authentication middleware.
+func WithRequestCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheKey{}, &requestCache{
+		permissions: make(map[int][]Permission),
+		merchants:   make(map[int][]int),
+		decisions:   make(map[decisionKey]bool),
+	})
+}
+
+func cacheFromContext(ctx context.Context) *requestCache {
+	cache, _ := ctx.Value(cacheKey{}).(*requestCache)
+	return cache
+}
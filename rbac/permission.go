@@ -0,0 +1,57 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	Wildcard = "*"
+	ScopeAny = "any"
+	ScopeOwn = "own"
+)
+
+// Permission is parsed from names of the form resource:action[:scope], e.g.
+// "product:write:own", "order:read" or "*". Resource and action accept the
+// "*" wildcard; a missing scope means "any".
+type Permission struct {
+	Resource string
+	Action   string
+	Scope    string
+}
+
+func ParsePermission(name string) (Permission, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == Wildcard {
+		return Permission{Resource: Wildcard, Action: Wildcard, Scope: ScopeAny}, nil
+	}
+
+	parts := strings.Split(name, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Permission{}, fmt.Errorf("invalid permission %q: expected resource:action[:scope]", name)
+	}
+
+	p := Permission{Resource: parts[0], Action: parts[1], Scope: ScopeAny}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case ScopeAny, ScopeOwn:
+			p.Scope = parts[2]
+		default:
+			return Permission{}, fmt.Errorf("invalid permission %q: unknown scope %q", name, parts[2])
+		}
+	}
+
+	return p, nil
+}
+
+func (p Permission) String() string {
+	if p.Scope == ScopeAny {
+		return p.Resource + ":" + p.Action
+	}
+	return p.Resource + ":" + p.Action + ":" + p.Scope
+}
+
+func (p Permission) matches(action, resourceType string) bool {
+	return (p.Resource == Wildcard || p.Resource == resourceType) &&
+		(p.Action == Wildcard || p.Action == action)
+}
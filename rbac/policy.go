@@ -0,0 +1,183 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Resource describes what is being accessed. OwnerID is the user that owns
+// the record directly (carts, reviews, orders) and MerchantID the merchant it
+// belongs to (products, merchant documents); either enables ":own" grants.
+type Resource struct {
+	Type       string
+	OwnerID    int
+	MerchantID int
+}
+
+type Store interface {
+	GetUserPermissionNames(ctx context.Context, userID int32) ([]string, error)
+	GetMerchantIDsByUserID(ctx context.Context, userID int32) ([]int32, error)
+}
+
+//go:generate mockgen -source=policy.go -destination=mocks/policy.go
+type Policy interface {
+	Can(ctx context.Context, userID int, action string, resource Resource) (bool, error)
+	Authorize(ctx context.Context, userID int, action string, resource Resource) error
+}
+
+type evaluator struct {
+	store Store
+}
+
+func NewPolicy(store Store) Policy {
+	return &evaluator{store: store}
+}
+
+func (e *evaluator) Authorize(ctx context.Context, userID int, action string, resource Resource) error {
+	ok, err := e.Can(ctx, userID, action, resource)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s on %s", ErrPermissionDenied, action, resource.Type)
+	}
+	return nil
+}
+
+func (e *evaluator) Can(ctx context.Context, userID int, action string, resource Resource) (bool, error) {
+	action = strings.ToLower(action)
+	resource.Type = strings.ToLower(resource.Type)
+
+	cache := cacheFromContext(ctx)
+	key := decisionKey{userID: userID, action: action, resource: resource}
+
+	if cache != nil {
+		cache.mu.Lock()
+		decision, ok := cache.decisions[key]
+		cache.mu.Unlock()
+		if ok {
+			return decision, nil
+		}
+	}
+
+	decision, err := e.evaluate(ctx, cache, userID, action, resource)
+	if err != nil {
+		return false, err
+	}
+
+	if cache != nil {
+		cache.mu.Lock()
+		cache.decisions[key] = decision
+		cache.mu.Unlock()
+	}
+
+	return decision, nil
+}
+
+func (e *evaluator) evaluate(ctx context.Context, cache *requestCache, userID int, action string, resource Resource) (bool, error) {
+	permissions, err := e.permissions(ctx, cache, userID)
+	if err != nil {
+		return false, err
+	}
+
+	needOwnership := false
+	for _, p := range permissions {
+		if !p.matches(action, resource.Type) {
+			continue
+		}
+		if p.Scope == ScopeAny {
+			return true, nil
+		}
+		needOwnership = true
+	}
+
+	if !needOwnership {
+		return false, nil
+	}
+
+	return e.owns(ctx, cache, userID, resource)
+}
+
+func (e *evaluator) owns(ctx context.Context, cache *requestCache, userID int, resource Resource) (bool, error) {
+	if resource.OwnerID != 0 && resource.OwnerID == userID {
+		return true, nil
+	}
+
+	if resource.MerchantID == 0 {
+		return false, nil
+	}
+
+	merchants, err := e.merchants(ctx, cache, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(merchants, resource.MerchantID), nil
+}
+
+func (e *evaluator) permissions(ctx context.Context, cache *requestCache, userID int) ([]Permission, error) {
+	if cache != nil {
+		cache.mu.Lock()
+		permissions, ok := cache.permissions[userID]
+		cache.mu.Unlock()
+		if ok {
+			return permissions, nil
+		}
+	}
+
+	names, err := e.store.GetUserPermissionNames(ctx, int32(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user permissions: %w", err)
+	}
+
+	permissions := make([]Permission, 0, len(names))
+	for _, name := range names {
+		p, err := ParsePermission(name)
+		if err != nil {
+			continue
+		}
+		permissions = append(permissions, p)
+	}
+
+	if cache != nil {
+		cache.mu.Lock()
+		cache.permissions[userID] = permissions
+		cache.mu.Unlock()
+	}
+
+	return permissions, nil
+}
+
+func (e *evaluator) merchants(ctx context.Context, cache *requestCache, userID int) ([]int, error) {
+	if cache != nil {
+		cache.mu.Lock()
+		merchants, ok := cache.merchants[userID]
+		cache.mu.Unlock()
+		if ok {
+			return merchants, nil
+		}
+	}
+
+	ids, err := e.store.GetMerchantIDsByUserID(ctx, int32(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user merchants: %w", err)
+	}
+
+	merchants := make([]int, 0, len(ids))
+	for _, id := range ids {
+		merchants = append(merchants, int(id))
+	}
+
+	if cache != nil {
+		cache.mu.Lock()
+		cache.merchants[userID] = merchants
+		cache.mu.Unlock()
+	}
+
+	return merchants, nil
+}
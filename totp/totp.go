@@ -0,0 +1,165 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	secretSize = 20
+	maxSkew    = 3
+)
+
+var (
+	ErrInvalidSecret = errors.New("invalid totp secret")
+	ErrInvalidConfig = errors.New("invalid totp config")
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Config holds the RFC 6238 parameters shared by enrolment and validation.
+// Only HMAC-SHA1 is supported since it is the one authenticator apps
+// reliably implement.
+type Config struct {
+	Issuer string
+	Period time.Duration
+	Digits int
+	// Skew is the number of periods accepted on either side of the current
+	// one to tolerate clock drift between server and device.
+	Skew int
+}
+
+func DefaultConfig(issuer string) Config {
+	return Config{
+		Issuer: issuer,
+		Period: 30 * time.Second,
+		Digits: 6,
+		Skew:   1,
+	}
+}
+
+// GenerateSecret returns a random 160-bit secret encoded as unpadded base32,
+// the form authenticator apps expect.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth:// URI to render as a QR code during enrolment.
+func (c Config) URI(secret string, account string) string {
+	label := account
+	if c.Issuer != "" {
+		label = c.Issuer + ":" + account
+	}
+
+	q := url.Values{}
+	q.Set("secret", secret)
+	if c.Issuer != "" {
+		q.Set("issuer", c.Issuer)
+	}
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(c.Digits))
+	q.Set("period", fmt.Sprint(int(c.Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + label,
+		RawQuery: strings.ReplaceAll(q.Encode(), "+", "%20"),
+	}
+
+	return u.String()
+}
+
+// Check reports a Config that authenticator apps cannot follow or that
+// would make Step divide by zero: Period must be a positive number of whole
+// seconds, Digits between 6 and 8 and Skew between 0 and 3.
+func (c Config) Check() error {
+	switch {
+	case c.Period < time.Second || c.Period%time.Second != 0:
+		return fmt.Errorf("%w: period must be a positive number of seconds", ErrInvalidConfig)
+	case c.Digits < 6 || c.Digits > 8:
+		return fmt.Errorf("%w: digits must be between 6 and 8", ErrInvalidConfig)
+	case c.Skew < 0 || c.Skew > maxSkew:
+		return fmt.Errorf("%w: skew must be between 0 and %d", ErrInvalidConfig, maxSkew)
+	}
+	return nil
+}
+
+func (c Config) Step(t time.Time) int64 {
+	return t.Unix() / int64(c.Period/time.Second)
+}
+
+// Code returns the code valid at t.
+func (c Config) Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, c.Step(t), c.Digits), nil
+}
+
+// Validate checks code against the periods around t and returns the step
+// it matched. Callers must reject a step that is not greater than the last
+// accepted one, otherwise a code can be replayed while it is still valid.
+func (c Config) Validate(secret string, code string, t time.Time) (int64, bool, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(code) != c.Digits {
+		return 0, false, nil
+	}
+
+	current := c.Step(t)
+	for i := -c.Skew; i <= c.Skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step, c.Digits)), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// hotp implements the HOTP algorithm of RFC 4226 with dynamic truncation.
+func hotp(key []byte, counter int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}